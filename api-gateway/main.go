@@ -1,9 +1,14 @@
 package main
 
 import (
+    "encoding/json"
     "log"
     "net/http"
+    "net/http/httputil"
+    "net/url"
     "os"
+    "path"
+    "strings"
 
     "github.com/gin-gonic/gin"
     "github.com/gin-contrib/cors"
@@ -15,7 +20,7 @@ func main() {
     // CORS configuration
     config := cors.DefaultConfig()
     config.AllowOrigins = []string{"http://localhost:4200", "http://frontend"}
-    config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+    config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
     config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
     router.Use(cors.New(config))
 
@@ -27,12 +32,19 @@ func main() {
         })
     })
 
-    // Proxy routes (to be implemented)
-    router.Any("/stakeholders/*path", proxyHandler("stakeholders-service:8081"))
-    router.Any("/content/*path", proxyHandler("content-service:8082"))
-    router.Any("/commerce/*path", proxyHandler("commerce-service:8083"))
-    router.Any("/follow/*path", proxyHandler("stakeholders-service:8081"))
-    router.Any("/can-comment/*path", proxyHandler("stakeholders-service:8081"))
+    // Upstream services - addresses come from the same env vars the services use
+    stakeholdersURL := serviceURL("STAKEHOLDERS_SERVICE_URL", "stakeholders-service:8081")
+    contentURL := serviceURL("CONTENT_SERVICE_URL", "content-service:8082")
+    commerceURL := serviceURL("COMMERCE_SERVICE_URL", "commerce-service:8083")
+
+    // Proxy routes
+    // /stakeholders, /content and /commerce are stripped before forwarding,
+    // /follow and /can-comment are real stakeholders routes and are kept as is
+    router.Any("/stakeholders/*path", proxyHandler(stakeholdersURL, true))
+    router.Any("/content/*path", proxyHandler(contentURL, true))
+    router.Any("/commerce/*path", proxyHandler(commerceURL, true))
+    router.Any("/follow/*path", proxyHandler(stakeholdersURL, false))
+    router.Any("/can-comment/*path", proxyHandler(stakeholdersURL, false))
 
     port := os.Getenv("PORT")
     if port == "" {
@@ -43,11 +55,68 @@ func main() {
     log.Fatal(router.Run(":" + port))
 }
 
-func proxyHandler(target string) gin.HandlerFunc {
+// serviceURL reads an upstream address from env, e.g. "stakeholders-service:8081".
+// The scheme is optional and defaults to http.
+func serviceURL(envKey, fallback string) *url.URL {
+    raw := os.Getenv(envKey)
+    if raw == "" {
+        raw = fallback
+    }
+    if !strings.Contains(raw, "://") {
+        raw = "http://" + raw
+    }
+
+    target, err := url.Parse(raw)
+    if err != nil {
+        log.Fatalf("Invalid %s %q: %v", envKey, raw, err)
+    }
+    return target
+}
+
+// proxyHandler forwards the request (method, headers, query and body) to target
+// and streams the upstream response back to the client.
+func proxyHandler(target *url.URL, stripPrefix bool) gin.HandlerFunc {
+    proxy := httputil.NewSingleHostReverseProxy(target)
+
+    director := proxy.Director
+    proxy.Director = func(req *http.Request) {
+        director(req)
+        req.Host = target.Host
+    }
+
+    // Flush immediately so streamed responses are not buffered by the gateway
+    proxy.FlushInterval = -1
+
+    // The gateway handles CORS itself, drop the upstream headers so browsers
+    // do not see duplicated Access-Control-* values
+    proxy.ModifyResponse = func(resp *http.Response) error {
+        for key := range resp.Header {
+            if strings.HasPrefix(key, "Access-Control-") {
+                resp.Header.Del(key)
+            }
+        }
+        return nil
+    }
+
+    proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+        log.Printf("Proxy error for %s %s -> %s: %v", req.Method, req.URL.Path, target.Host, err)
+        w.Header().Set("Content-Type", "application/json; charset=utf-8")
+        w.WriteHeader(http.StatusBadGateway)
+        json.NewEncoder(w).Encode(gin.H{"error": "Upstream service unavailable"})
+    }
+
     return func(c *gin.Context) {
-        c.JSON(http.StatusOK, gin.H{
-            "message": "Proxy to " + target + " - Service under development",
-            "path": c.Request.URL.Path,
-        })
+        if stripPrefix {
+            c.Request.URL.Path = c.Param("path")
+            c.Request.URL.RawPath = ""
+        }
+
+        // Service-to-service endpoints are never reachable from outside
+        if cleaned := path.Clean(c.Request.URL.Path); cleaned == "/internal" || strings.HasPrefix(cleaned, "/internal/") {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+            return
+        }
+
+        proxy.ServeHTTP(c.Writer, c.Request)
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gin context keys holding the caller identity
+const (
+	ContextUserID   = "user_id"
+	ContextUsername = "username"
+	ContextRole     = "role"
+
+	ContextEmailVerified = "email_verified"
+)
+
+// Middleware rejects requests without a valid bearer token and stores
+// the caller identity in the gin context
+func Middleware(v *Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, err := BearerToken(c.GetHeader("Authorization"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		claims, err := v.Verify(tokenString)
+		if errors.Is(err, ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return
+		}
+		if errors.Is(err, ErrInvalidToken) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error verifying session: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify session"})
+			return
+		}
+
+		setIdentity(c, claims)
+		c.Next()
+	}
+}
+
+// OptionalMiddleware sets the caller identity when a valid token is present,
+// anonymous requests pass through. A present but invalid token is still rejected.
+func OptionalMiddleware(v *Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		Middleware(v)(c)
+	}
+}
+
+func setIdentity(c *gin.Context, claims *Claims) {
+	c.Set(ContextUserID, claims.UserID)
+	c.Set(ContextUsername, claims.Username)
+	c.Set(ContextRole, claims.Role)
+	c.Set(ContextEmailVerified, claims.EmailVerified)
+}
+
+// UserID returns the authenticated user id, false for anonymous requests
+func UserID(c *gin.Context) (int, bool) {
+	id := c.GetInt(ContextUserID)
+	return id, id > 0
+}
+
+// EmailVerified reports whether the authenticated user verified their email
+func EmailVerified(c *gin.Context) bool {
+	return c.GetBool(ContextEmailVerified)
+}
+
+// Role returns the authenticated user role, empty for anonymous requests
+func Role(c *gin.Context) string {
+	return c.GetString(ContextRole)
+}
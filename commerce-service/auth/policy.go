@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles from the users.role ENUM
+const (
+	RoleAdmin   = "admin"
+	RoleGuide   = "guide"
+	RoleTourist = "tourist"
+)
+
+// Route policies below run after Middleware, which sets the caller identity.
+// Every denial uses Forbidden so clients get the same 403 body everywhere.
+
+// RequireRole allows only callers with one of the given roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := Role(c)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		Forbidden(c, "This action requires role: "+strings.Join(roles, " or "))
+	}
+}
+
+// RequireOwnerOrAdmin allows the caller when the user id in the given route
+// param is their own, admins are always allowed
+func RequireOwnerOrAdmin(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Role(c) == RoleAdmin {
+			c.Next()
+			return
+		}
+
+		userID, ok := UserID(c)
+		ownerID, err := strconv.Atoi(c.Param(param))
+		if !ok || err != nil || ownerID != userID {
+			Forbidden(c, "You can only access your own resources")
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsOwnerOrAdmin is the in-handler variant for resources whose owner is only
+// known after loading them
+func IsOwnerOrAdmin(c *gin.Context, ownerID int) bool {
+	userID, ok := UserID(c)
+	return Role(c) == RoleAdmin || (ok && userID == ownerID)
+}
+
+// RequireVerifiedEmail allows only callers whose email is verified. The
+// distinct code lets clients offer to resend the verification mail.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !EmailVerified(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Please verify your email address first",
+				"code":  "email_not_verified",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Forbidden aborts with the common 403 response
+func Forbidden(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error": message,
+		"code":  "forbidden",
+	})
+}
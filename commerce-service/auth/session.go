@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStakeholdersURL = "stakeholders-service:8081"
+
+	// Longest a revoked session, block or role change stays unnoticed here
+	sessionCacheTTL     = 5 * time.Second
+	maxCachedSessions   = 10000
+	sessionCheckTimeout = 3 * time.Second
+)
+
+type sessionEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// RemoteSessionCheck asks stakeholders-service, which owns the sessions,
+// whether the session behind a token is still active. Answers are cached
+// for a few seconds so a burst of requests costs one lookup.
+func RemoteSessionCheck(baseURL string) SessionCheck {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	client := &http.Client{Timeout: sessionCheckTimeout}
+
+	var mu sync.Mutex
+	cache := make(map[int64]sessionEntry)
+
+	return func(claims *Claims) (bool, error) {
+		if claims.SessionID == 0 {
+			return false, nil
+		}
+
+		now := time.Now()
+		mu.Lock()
+		entry, ok := cache[claims.SessionID]
+		mu.Unlock()
+		if ok && now.Before(entry.expiresAt) {
+			return entry.active, nil
+		}
+
+		url := fmt.Sprintf("%s/internal/sessions/%d?user_id=%d", baseURL, claims.SessionID, claims.UserID)
+		resp, err := client.Get(url)
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return false, fmt.Errorf("stakeholders-service responded %d to session check", resp.StatusCode)
+		}
+		var status struct {
+			Active bool `json:"active"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			return false, fmt.Errorf("unexpected session check response: %w", err)
+		}
+
+		mu.Lock()
+		if len(cache) >= maxCachedSessions {
+			cache = make(map[int64]sessionEntry)
+		}
+		cache[claims.SessionID] = sessionEntry{active: status.Active, expiresAt: now.Add(sessionCacheTTL)}
+		mu.Unlock()
+		return status.Active, nil
+	}
+}
+
+// RemoteSessionCheckFromEnv checks sessions at STAKEHOLDERS_SERVICE_URL,
+// defaulting to the compose service
+func RemoteSessionCheckFromEnv() SessionCheck {
+	baseURL := os.Getenv("STAKEHOLDERS_SERVICE_URL")
+	if baseURL == "" {
+		baseURL = defaultStakeholdersURL
+	}
+	return RemoteSessionCheck(baseURL)
+}
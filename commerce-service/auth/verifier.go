@@ -0,0 +1,109 @@
+// Package auth holds the JWT access token handling shared by all services.
+// stakeholders-service issues the tokens, every service verifies them with
+// the same JWT_SECRET instead of trusting the X-User-ID header.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer written into and required from every access token
+const Issuer = "soa-tours-stakeholders"
+
+// Default secret used only when JWT_SECRET is not set (local development)
+const devSecret = "your-super-secret-jwt-key-here-make-it-long-and-secure"
+
+var (
+	ErrMissingToken   = errors.New("missing bearer token")
+	ErrInvalidToken   = errors.New("invalid or expired token")
+	ErrSessionRevoked = errors.New("session has been revoked")
+)
+
+// Claims carried by an access token
+type Claims struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	// Unverified accounts cannot comment or purchase
+	EmailVerified bool `json:"email_verified"`
+	// Refresh token session the access token was issued for
+	SessionID int64 `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SessionCheck reports whether the session behind a token is still active.
+// Only the issuing service has the session store, other services rely on
+// the short access token lifetime.
+type SessionCheck func(claims *Claims) (bool, error)
+
+type Verifier struct {
+	secret       []byte
+	sessionCheck SessionCheck
+}
+
+func NewVerifier(secret string) *Verifier {
+	return &Verifier{secret: []byte(secret)}
+}
+
+// WithSessionCheck makes Verify reject tokens of revoked sessions
+func (v *Verifier) WithSessionCheck(check SessionCheck) *Verifier {
+	v.sessionCheck = check
+	return v
+}
+
+// SecretFromEnv returns JWT_SECRET, falling back to the development default
+func SecretFromEnv() string {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Println("JWT_SECRET not set, using development secret")
+		secret = devSecret
+	}
+	return secret
+}
+
+// Verify checks signature, algorithm, issuer and expiry and returns the claims
+func (v *Verifier) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		func(t *jwt.Token) (interface{}, error) {
+			return v.secret, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.UserID <= 0 {
+		return nil, fmt.Errorf("%w: missing user id", ErrInvalidToken)
+	}
+
+	if v.sessionCheck != nil {
+		active, err := v.sessionCheck(claims)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrSessionRevoked
+		}
+	}
+	return claims, nil
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>" header value
+func BearerToken(header string) (string, error) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
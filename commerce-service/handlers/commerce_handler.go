@@ -1,12 +1,12 @@
 package handlers
 
 import (
+    "commerce-service/auth"
     "commerce-service/database"
     "commerce-service/models"
     "crypto/rand"
     "encoding/hex"
     "net/http"
-    "strconv"
 
     "github.com/gin-gonic/gin"
 )
@@ -21,10 +21,9 @@ func NewCommerceHandler(db *database.DB) *CommerceHandler {
 
 // GET /cart - Get user's shopping cart
 func (h *CommerceHandler) GetCart(c *gin.Context) {
-    userIDStr := c.GetHeader("X-User-ID")
-    userID, err := strconv.Atoi(userIDStr)
-    if err != nil || userID <= 0 {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+    userID, ok := auth.UserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
         return
     }
 
@@ -51,10 +50,9 @@ func (h *CommerceHandler) GetCart(c *gin.Context) {
 
 // POST /cart/add - Add tour to cart
 func (h *CommerceHandler) AddToCart(c *gin.Context) {
-    userIDStr := c.GetHeader("X-User-ID")
-    userID, err := strconv.Atoi(userIDStr)
-    if err != nil || userID <= 0 {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+    userID, ok := auth.UserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
         return
     }
 
@@ -117,10 +115,9 @@ func (h *CommerceHandler) AddToCart(c *gin.Context) {
 
 // DELETE /cart/remove/:tourId - Remove tour from cart
 func (h *CommerceHandler) RemoveFromCart(c *gin.Context) {
-    userIDStr := c.GetHeader("X-User-ID")
-    userID, err := strconv.Atoi(userIDStr)
-    if err != nil || userID <= 0 {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+    userID, ok := auth.UserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
         return
     }
 
@@ -163,10 +160,9 @@ func (h *CommerceHandler) RemoveFromCart(c *gin.Context) {
 
 // POST /checkout - Checkout cart and create purchase tokens
 func (h *CommerceHandler) Checkout(c *gin.Context) {
-    userIDStr := c.GetHeader("X-User-ID")
-    userID, err := strconv.Atoi(userIDStr)
-    if err != nil || userID <= 0 {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+    userID, ok := auth.UserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
         return
     }
 
@@ -217,10 +213,9 @@ func (h *CommerceHandler) Checkout(c *gin.Context) {
 
 // GET /purchases - Get user's purchased tours
 func (h *CommerceHandler) GetPurchases(c *gin.Context) {
-    userIDStr := c.GetHeader("X-User-ID")
-    userID, err := strconv.Atoi(userIDStr)
-    if err != nil || userID <= 0 {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+    userID, ok := auth.UserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
         return
     }
 
@@ -258,17 +253,16 @@ func (h *CommerceHandler) GetPurchases(c *gin.Context) {
 
 // GET /purchase/check/:tourId - Check if user purchased specific tour
 func (h *CommerceHandler) CheckTourPurchase(c *gin.Context) {
-    userIDStr := c.GetHeader("X-User-ID")
-    userID, err := strconv.Atoi(userIDStr)
-    if err != nil || userID <= 0 {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+    userID, ok := auth.UserID(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
         return
     }
 
     tourID := c.Param("tourId")
 
     var token string
-    err = h.DB.QueryRow(`
+    err := h.DB.QueryRow(`
         SELECT token FROM purchase_tokens 
         WHERE user_id = ? AND tour_id = ? AND is_active = true
         LIMIT 1`,
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+    "commerce-service/models"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Personal data export and erasure, orchestrated by stakeholders-service
+
+// GET /internal/users/:id/export - carts with items and all purchase tokens of the user
+func (h *CommerceHandler) ExportUserData(c *gin.Context) {
+    userID, err := strconv.Atoi(c.Param("id"))
+    if err != nil || userID <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+        return
+    }
+
+    rows, err := h.DB.Query(`
+        SELECT id, user_id, total_price, created_at, updated_at
+        FROM shopping_carts WHERE user_id = ?`,
+        userID,
+    )
+    if err != nil {
+        log.Printf("Error exporting carts: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+    carts := []models.ShoppingCart{}
+    for rows.Next() {
+        var cart models.ShoppingCart
+        if err := rows.Scan(&cart.ID, &cart.UserID, &cart.TotalPrice, &cart.CreatedAt, &cart.UpdatedAt); err != nil {
+            log.Printf("Error scanning cart: %v", err)
+            continue
+        }
+        carts = append(carts, cart)
+    }
+    rows.Close()
+
+    for i := range carts {
+        items, err := h.getCartItems(carts[i].ID)
+        if err != nil {
+            log.Printf("Error exporting cart items: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+            return
+        }
+        carts[i].Items = items
+    }
+
+    // Inactive tokens are included too, the export covers the whole history
+    rows, err = h.DB.Query(`
+        SELECT id, user_id, tour_id, token, purchased_at, expires_at, is_active
+        FROM purchase_tokens WHERE user_id = ?
+        ORDER BY purchased_at DESC`,
+        userID,
+    )
+    if err != nil {
+        log.Printf("Error exporting purchase tokens: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+    defer rows.Close()
+
+    tokens := []models.PurchaseToken{}
+    for rows.Next() {
+        var token models.PurchaseToken
+        err := rows.Scan(
+            &token.ID, &token.UserID, &token.TourID, &token.Token,
+            &token.PurchasedAt, &token.ExpiresAt, &token.IsActive,
+        )
+        if err != nil {
+            log.Printf("Error scanning purchase token: %v", err)
+            continue
+        }
+        tokens = append(tokens, token)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "service":         "commerce-service",
+        "user_id":         userID,
+        "exported_at":     time.Now(),
+        "shopping_carts":  carts,
+        "purchase_tokens": tokens,
+    })
+}
+
+// DELETE /internal/users/:id/data - delete carts, cart items and purchase tokens
+func (h *CommerceHandler) DeleteUserData(c *gin.Context) {
+    userID, err := strconv.Atoi(c.Param("id"))
+    if err != nil || userID <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+        return
+    }
+
+    tx, err := h.DB.Begin()
+    if err != nil {
+        log.Printf("Error starting transaction: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+    defer tx.Rollback()
+
+    summary := gin.H{}
+    statements := []struct {
+        name  string
+        query string
+    }{
+        {"cart_items_deleted", "DELETE ci FROM cart_items ci JOIN shopping_carts sc ON ci.cart_id = sc.id WHERE sc.user_id = ?"},
+        {"shopping_carts_deleted", "DELETE FROM shopping_carts WHERE user_id = ?"},
+        {"purchase_tokens_deleted", "DELETE FROM purchase_tokens WHERE user_id = ?"},
+    }
+    for _, s := range statements {
+        result, err := tx.Exec(s.query, userID)
+        if err != nil {
+            log.Printf("Error deleting user data (%s): %v", s.name, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user data"})
+            return
+        }
+        summary[s.name], _ = result.RowsAffected()
+    }
+
+    if err = tx.Commit(); err != nil {
+        log.Printf("Error committing transaction: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user data"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "service": "commerce-service",
+        "user_id": userID,
+        "summary": summary,
+    })
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+    "commerce-service/auth"
     "commerce-service/database"
     "commerce-service/handlers"
     "log"
@@ -16,7 +17,7 @@ func CORSMiddleware() gin.HandlerFunc {
     return func(c *gin.Context) {
         c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
         c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-        c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-User-ID")
+        c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
         c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
         if c.Request.Method == "OPTIONS" {
@@ -28,8 +29,9 @@ func CORSMiddleware() gin.HandlerFunc {
     }
 }
 
-// Auth middleware to check X-User-ID header
-func AuthMiddleware() gin.HandlerFunc {
+// Auth middleware - validates the bearer token issued by stakeholders-service
+func AuthMiddleware(verifier *auth.Verifier) gin.HandlerFunc {
+    requireAuth := auth.Middleware(verifier)
     return func(c *gin.Context) {
         // Skip auth for health check
         if c.Request.URL.Path == "/health" {
@@ -37,13 +39,7 @@ func AuthMiddleware() gin.HandlerFunc {
             return
         }
 
-        userID := c.GetHeader("X-User-ID")
-        if userID == "" {
-            c.JSON(http.StatusUnauthorized, gin.H{"error": "X-User-ID header required"})
-            c.Abort()
-            return
-        }
-        c.Next()
+        requireAuth(c)
     }
 }
 
@@ -69,7 +65,9 @@ func main() {
 
     // Add middlewares
     router.Use(CORSMiddleware())
-    router.Use(AuthMiddleware())
+    // Sessions are checked with stakeholders-service, so a revoke, block or
+    // role change applies here within seconds rather than at token expiry
+    router.Use(AuthMiddleware(auth.NewVerifier(auth.SecretFromEnv()).WithSessionCheck(auth.RemoteSessionCheckFromEnv())))
 
     // Health check endpoint (no auth required)
     router.GET("/health", func(c *gin.Context) {
@@ -79,18 +77,26 @@ func main() {
         })
     })
 
+    verifiedOnly := auth.RequireVerifiedEmail()
+
     // Shopping cart endpoints
     router.GET("/cart", commerceHandler.GetCart)
     router.POST("/cart/add", commerceHandler.AddToCart)
     router.DELETE("/cart/remove/:tourId", commerceHandler.RemoveFromCart)
 
     // Checkout endpoint
-    router.POST("/checkout", commerceHandler.Checkout)
+    router.POST("/checkout", verifiedOnly, commerceHandler.Checkout)
 
     // Purchase management endpoints
     router.GET("/purchases", commerceHandler.GetPurchases)
     router.GET("/purchase/check/:tourId", commerceHandler.CheckTourPurchase)
 
+    // Personal data export and erasure, called by stakeholders-service with the
+    // caller's token. /internal is not exposed through the api-gateway.
+    internal := router.Group("/internal", auth.RequireOwnerOrAdmin("id"))
+    internal.GET("/users/:id/export", commerceHandler.ExportUserData)
+    internal.DELETE("/users/:id/data", commerceHandler.DeleteUserData)
+
     port := os.Getenv("PORT")
     if port == "" {
         port = "8083"
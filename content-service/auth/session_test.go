@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRemoteSessionCheck(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/internal/sessions/1":
+			fmt.Fprint(w, `{"active": true}`)
+		case "/internal/sessions/2":
+			fmt.Fprint(w, `{"active": false}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	check := RemoteSessionCheck(server.URL)
+
+	tests := []struct {
+		name      string
+		sessionID int64
+		active    bool
+		wantErr   bool
+	}{
+		{"active", 1, true, false},
+		{"cached", 1, true, false},
+		{"revoked", 2, false, false},
+		{"no session", 0, false, false},
+		{"service error", 3, false, true},
+	}
+	for _, tt := range tests {
+		active, err := check(&Claims{UserID: 7, SessionID: tt.sessionID})
+		if (err != nil) != tt.wantErr || active != tt.active {
+			t.Errorf("%s: got %v, %v", tt.name, active, err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("stakeholders-service called %d times, want 3", got)
+	}
+}
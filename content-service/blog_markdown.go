@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"content-service/markdown"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Blog descriptions are Markdown. The sanitized HTML and the plain-text
+// excerpt are rendered on every write and stored next to the source.
+
+type PreviewBlogRequest struct {
+	Description string `json:"description" binding:"required,min=1"`
+}
+
+// renderedDescription returns the fields to store for a description
+func renderedDescription(description string) (bson.M, error) {
+	rendered, err := markdown.Render(description)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{
+		"description_html": rendered.HTML,
+		"excerpt":          rendered.Excerpt,
+	}, nil
+}
+
+// POST /blogs/preview - render Markdown the way a saved blog would be shown
+func previewBlog(c *gin.Context) {
+	var req PreviewBlogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rendered, err := markdown.Render(req.Description)
+	if err != nil {
+		log.Printf("Error rendering preview: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render description"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"description_html": rendered.HTML,
+		"excerpt":          rendered.Excerpt,
+	})
+}
+
+// backfillRenderedDescriptions renders blogs saved before descriptions were Markdown
+func backfillRenderedDescriptions(ctx context.Context) {
+	cursor, err := blogsCollection.Find(ctx,
+		bson.M{"description_html": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"description": 1}),
+	)
+	if err != nil {
+		log.Printf("Error finding blogs to render: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var updates []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var blog Blog
+		if err := cursor.Decode(&blog); err != nil {
+			log.Printf("Error decoding blog for rendering: %v", err)
+			continue
+		}
+		fields, err := renderedDescription(blog.Description)
+		if err != nil {
+			log.Printf("Error rendering blog %s: %v", blog.ID.Hex(), err)
+			continue
+		}
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": blog.ID}).
+			SetUpdate(bson.M{"$set": fields}))
+	}
+	if len(updates) == 0 {
+		return
+	}
+
+	if _, err := blogsCollection.BulkWrite(ctx, updates); err != nil {
+		log.Printf("Error storing rendered descriptions: %v", err)
+		return
+	}
+	log.Printf("Rendered descriptions of %d blogs", len(updates))
+}
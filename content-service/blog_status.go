@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"content-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Blogs start as drafts that only the author sees. Published blogs are
+// public, closed blogs stay readable but take no new comments or likes.
+const (
+	blogStatusDraft     = "draft"
+	blogStatusPublished = "published"
+	blogStatusClosed    = "closed"
+)
+
+// Allowed status changes. A published blog cannot go back to draft since
+// others may already have liked or commented on it.
+var blogTransitions = map[string][]string{
+	blogStatusDraft:     {blogStatusPublished},
+	blogStatusPublished: {blogStatusClosed},
+	blogStatusClosed:    {blogStatusPublished},
+}
+
+func canTransitionBlog(from, to string) bool {
+	for _, allowed := range blogTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// blogStatusUpdate validates a status change and returns the fields to set
+func blogStatusUpdate(blog *Blog, status string, now time.Time) (bson.M, error) {
+	if !canTransitionBlog(blog.Status, status) {
+		return nil, fmt.Errorf("cannot change blog status from %s to %s", blog.Status, status)
+	}
+
+	update := bson.M{"status": status}
+	switch status {
+	case blogStatusPublished:
+		if blog.PublishedAt == nil {
+			update["published_at"] = now
+		}
+		update["closed_at"] = nil
+	case blogStatusClosed:
+		update["closed_at"] = now
+	}
+	return update, nil
+}
+
+// visibleBlogsFilter hides deleted and moderated blogs, and drafts from everyone except their author
+func visibleBlogsFilter(c *gin.Context) bson.M {
+	notDeleted := bson.M{"deleted_at": bson.M{"$exists": false}, "hidden_at": bson.M{"$exists": false}}
+	if userID, ok := auth.UserID(c); ok {
+		return bson.M{"$and": bson.A{notDeleted, bson.M{"$or": bson.A{
+			bson.M{"status": bson.M{"$ne": blogStatusDraft}},
+			bson.M{"author_id": userID},
+		}}}}
+	}
+	return bson.M{"$and": bson.A{notDeleted, bson.M{"status": bson.M{"$ne": blogStatusDraft}}}}
+}
+
+// findVisibleBlog loads a blog the caller may see, writing 404 or 500 otherwise.
+// Deleted blogs and someone else's draft are reported as missing, admins see
+// drafts. Blogs hidden by a moderator are missing for everyone but admins.
+func findVisibleBlog(ctx context.Context, c *gin.Context, id primitive.ObjectID) (*Blog, bool) {
+	var blog Blog
+	err := blogsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&blog)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return nil, false
+		}
+		log.Printf("Error finding blog: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+
+	if blog.DeletedAt != nil ||
+		(blog.Status == blogStatusDraft && !auth.IsOwnerOrAdmin(c, blog.AuthorID)) ||
+		(blog.HiddenAt != nil && auth.Role(c) != auth.RoleAdmin) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+		return nil, false
+	}
+	return &blog, true
+}
+
+// requireOpenBlog rejects interactions with blogs that are not published
+func requireOpenBlog(c *gin.Context, blog *Blog) bool {
+	switch blog.Status {
+	case blogStatusPublished:
+		return true
+	case blogStatusClosed:
+		c.JSON(http.StatusConflict, gin.H{"error": "Blog is closed"})
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "Blog is not published"})
+	}
+	return false
+}
+
+// backfillBlogStatus marks blogs created before statuses existed as
+// published, they were public until now
+func backfillBlogStatus(ctx context.Context) {
+	result, err := blogsCollection.UpdateMany(ctx,
+		bson.M{"status": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"status": blogStatusPublished}},
+	)
+	if err != nil {
+		log.Printf("Error backfilling blog status: %v", err)
+		return
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Marked %d existing blogs as published", result.ModifiedCount)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanTransitionBlog(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{blogStatusDraft, blogStatusPublished, true},
+		{blogStatusDraft, blogStatusClosed, false},
+		{blogStatusDraft, blogStatusDraft, false},
+		{blogStatusPublished, blogStatusClosed, true},
+		{blogStatusPublished, blogStatusDraft, false},
+		{blogStatusPublished, blogStatusPublished, false},
+		{blogStatusClosed, blogStatusPublished, true},
+		{blogStatusClosed, blogStatusDraft, false},
+		{"", blogStatusPublished, false},
+		{blogStatusDraft, "archived", false},
+	}
+	for _, tt := range tests {
+		if got := canTransitionBlog(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransitionBlog(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestBlogStatusUpdate(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	earlier := now.Add(-time.Hour)
+
+	update, err := blogStatusUpdate(&Blog{Status: blogStatusDraft}, blogStatusPublished, now)
+	if err != nil {
+		t.Fatalf("publish draft: %v", err)
+	}
+	if update["published_at"] != now {
+		t.Errorf("first publish published_at = %v, want %v", update["published_at"], now)
+	}
+
+	update, err = blogStatusUpdate(&Blog{Status: blogStatusClosed, PublishedAt: &earlier}, blogStatusPublished, now)
+	if err != nil {
+		t.Fatalf("reopen closed: %v", err)
+	}
+	if _, ok := update["published_at"]; ok {
+		t.Errorf("reopening changed published_at to %v", update["published_at"])
+	}
+	if update["closed_at"] != nil {
+		t.Errorf("reopening kept closed_at %v", update["closed_at"])
+	}
+
+	update, err = blogStatusUpdate(&Blog{Status: blogStatusPublished, PublishedAt: &earlier}, blogStatusClosed, now)
+	if err != nil {
+		t.Fatalf("close published: %v", err)
+	}
+	if update["closed_at"] != now {
+		t.Errorf("close closed_at = %v, want %v", update["closed_at"], now)
+	}
+
+	if _, err := blogStatusUpdate(&Blog{Status: blogStatusPublished}, blogStatusDraft, now); err == nil {
+		t.Error("published to draft was allowed")
+	}
+}
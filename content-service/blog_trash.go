@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"content-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Deleted blogs keep their document with deleted_at set. Once the restore
+// window is over a background job purges them together with their likes and
+// reports, which a TTL index would leave behind.
+const (
+	blogRestoreWindow = 30 * 24 * time.Hour
+	blogPurgeInterval = time.Hour
+)
+
+// DeletedBlog is a blog in the trash with the time it can be restored until
+type DeletedBlog struct {
+	Blog         `bson:",inline"`
+	RestoreUntil time.Time `json:"restore_until" bson:"-"`
+}
+
+// ensureBlogTrashIndex indexes deleted_at for the trash listing and the purge.
+// The TTL index earlier versions purged with is dropped first, it has the same keys.
+func ensureBlogTrashIndex(ctx context.Context) {
+	_, err := blogsCollection.Indexes().DropOne(ctx, "deleted_at_ttl")
+	var cmdErr mongo.CommandError
+	if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Code == indexNotFoundCode) {
+		log.Printf("Error dropping blog trash TTL index: %v", err)
+	}
+
+	_, err = blogsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Printf("Error creating blog trash index: %v", err)
+	}
+}
+
+// Server error code of dropping an index that does not exist
+const indexNotFoundCode = 27
+
+// startBlogPurge purges expired blogs now and every blogPurgeInterval
+func startBlogPurge() {
+	go func() {
+		ticker := time.NewTicker(blogPurgeInterval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			purgeDeletedBlogs(ctx)
+			cancel()
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeDeletedBlogs removes blogs past the restore window with their likes and
+// reports. Dependents go first, so a failed run leaves nothing orphaned and the
+// next one finishes it. Expired blogs can no longer be restored, so none of
+// them comes back in between.
+func purgeDeletedBlogs(ctx context.Context) {
+	cutoff := time.Now().Add(-blogRestoreWindow)
+	cursor, err := blogsCollection.Find(ctx,
+		bson.M{"deleted_at": bson.M{"$lte": cutoff}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		log.Printf("Error finding expired blogs: %v", err)
+		return
+	}
+	var expired []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &expired); err != nil {
+		log.Printf("Error decoding expired blogs: %v", err)
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+	ids := make([]primitive.ObjectID, len(expired))
+	for i, blog := range expired {
+		ids[i] = blog.ID
+	}
+
+	for _, dependents := range []*mongo.Collection{blogLikesCollection, reportsCollection} {
+		if _, err := dependents.DeleteMany(ctx, bson.M{"blog_id": bson.M{"$in": ids}}); err != nil {
+			log.Printf("Error purging %s of expired blogs: %v", dependents.Name(), err)
+			return
+		}
+	}
+	result, err := blogsCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$lte": cutoff}})
+	if err != nil {
+		log.Printf("Error purging expired blogs: %v", err)
+		return
+	}
+	log.Printf("Purged %d blogs past the restore window", result.DeletedCount)
+}
+
+// GET /blogs/deleted?author_id= - the caller's deleted blogs that can still be
+// restored, admins may list any author's
+func getDeletedBlogs(c *gin.Context) {
+	authorID, _ := auth.UserID(c)
+	if authorIDStr := c.Query("author_id"); authorIDStr != "" {
+		id, err := strconv.Atoi(authorIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+			return
+		}
+		if !auth.IsOwnerOrAdmin(c, id) {
+			auth.Forbidden(c, "You can only list your own deleted blogs")
+			return
+		}
+		authorID = id
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"author_id":  authorID,
+		"deleted_at": bson.M{"$gt": time.Now().Add(-blogRestoreWindow)},
+	}
+	cursor, err := blogsCollection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}}).
+		SetProjection(blogListProjection))
+	if err != nil {
+		log.Printf("Error finding deleted blogs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	blogs := []DeletedBlog{}
+	if err = cursor.All(ctx, &blogs); err != nil {
+		log.Printf("Error decoding deleted blogs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	for i := range blogs {
+		blogs[i].RestoreUntil = blogs[i].DeletedAt.Add(blogRestoreWindow)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blogs": blogs, "count": len(blogs)})
+}
+
+// POST /blogs/:id/restore - take a blog out of the trash, owner or admin only
+func restoreBlog(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blog ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var blog Blog
+	err = blogsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&blog)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return
+		}
+		log.Printf("Error finding blog: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !auth.IsOwnerOrAdmin(c, blog.AuthorID) {
+		auth.Forbidden(c, "You can only restore your own blogs")
+		return
+	}
+	if blog.DeletedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Blog is not deleted"})
+		return
+	}
+	// The purge job only runs every blogPurgeInterval, so an expired blog may still be here
+	if time.Since(*blog.DeletedAt) > blogRestoreWindow {
+		c.JSON(http.StatusGone, gin.H{"error": "Restore window has passed"})
+		return
+	}
+
+	_, err = blogsCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$unset": bson.M{"deleted_at": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Error restoring blog: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore blog"})
+		return
+	}
+
+	blog.DeletedAt = nil
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Blog restored successfully",
+		"blog":    blog,
+	})
+}
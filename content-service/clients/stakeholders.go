@@ -0,0 +1,286 @@
+// Package clients holds typed HTTP clients for the other SOA Tours services.
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStakeholdersURL = "stakeholders-service:8081"
+
+	requestTimeout = 3 * time.Second
+	maxAttempts    = 3
+	retryBackoff   = 100 * time.Millisecond
+
+	// Short enough that a fresh follow is picked up almost immediately
+	permissionCacheTTL = 15 * time.Second
+	maxCacheEntries    = 10000
+)
+
+// CommentPair asks whether UserID may comment on blogs of AuthorID
+type CommentPair struct {
+	UserID   int `json:"user_id"`
+	AuthorID int `json:"author_id"`
+}
+
+type cacheEntry struct {
+	canComment bool
+	expiresAt  time.Time
+}
+
+// StakeholdersClient calls stakeholders-service with per-attempt timeouts,
+// retries on network errors and 5xx, and caches comment permissions briefly
+type StakeholdersClient struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	cache     map[CommentPair]cacheEntry
+	following map[int]followingEntry
+}
+
+type followingEntry struct {
+	ids       []int
+	expiresAt time.Time
+}
+
+// NewStakeholdersClient creates a client for the given host:port or URL
+func NewStakeholdersClient(baseURL string) *StakeholdersClient {
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "http://" + baseURL
+	}
+	return &StakeholdersClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: requestTimeout},
+		cache:      make(map[CommentPair]cacheEntry),
+		following:  make(map[int]followingEntry),
+	}
+}
+
+// NewStakeholdersClientFromEnv uses STAKEHOLDERS_SERVICE_URL, defaulting to the compose service
+func NewStakeholdersClientFromEnv() *StakeholdersClient {
+	baseURL := os.Getenv("STAKEHOLDERS_SERVICE_URL")
+	if baseURL == "" {
+		baseURL = defaultStakeholdersURL
+	}
+	return NewStakeholdersClient(baseURL)
+}
+
+// CanComment reports whether userID may comment on a blog by authorID.
+// The caller's Authorization header is forwarded so stakeholders-service sees the same user.
+func (c *StakeholdersClient) CanComment(ctx context.Context, authHeader string, userID, authorID int) (bool, error) {
+	pair := CommentPair{UserID: userID, AuthorID: authorID}
+	results, err := c.CanCommentBatch(ctx, authHeader, []CommentPair{pair})
+	if err != nil {
+		return false, err
+	}
+	return results[pair], nil
+}
+
+// CanCommentBatch answers many pairs with at most one request, cached pairs are not sent
+func (c *StakeholdersClient) CanCommentBatch(ctx context.Context, authHeader string, pairs []CommentPair) (map[CommentPair]bool, error) {
+	results := make(map[CommentPair]bool, len(pairs))
+	var missing []CommentPair
+
+	now := time.Now()
+	c.mu.Lock()
+	for _, pair := range pairs {
+		if _, seen := results[pair]; seen {
+			continue
+		}
+		if pair.UserID == pair.AuthorID {
+			results[pair] = true
+			continue
+		}
+		if entry, ok := c.cache[pair]; ok && now.Before(entry.expiresAt) {
+			results[pair] = entry.canComment
+			continue
+		}
+		results[pair] = false
+		missing = append(missing, pair)
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	var response struct {
+		Results []struct {
+			CommentPair
+			CanComment bool `json:"can_comment"`
+		} `json:"results"`
+	}
+	body := map[string]interface{}{"pairs": missing}
+	if err := c.doJSON(ctx, http.MethodPost, "/can-comment/batch", authHeader, body, &response); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(permissionCacheTTL)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache)+len(response.Results) > maxCacheEntries {
+		c.pruneLocked()
+	}
+	for _, r := range response.Results {
+		results[r.CommentPair] = r.CanComment
+		c.cache[r.CommentPair] = cacheEntry{canComment: r.CanComment, expiresAt: expiresAt}
+	}
+
+	return results, nil
+}
+
+// FollowingIDs returns the IDs of users that userID follows. userID must be
+// the caller identified by authHeader, stakeholders-service answers for the token.
+func (c *StakeholdersClient) FollowingIDs(ctx context.Context, authHeader string, userID int) ([]int, error) {
+	c.mu.Lock()
+	entry, ok := c.following[userID]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.ids, nil
+	}
+
+	var response struct {
+		UserIDs []int `json:"user_ids"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/following/ids", authHeader, nil, &response); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.following) >= maxCacheEntries {
+		c.following = make(map[int]followingEntry)
+	}
+	c.following[userID] = followingEntry{ids: response.UserIDs, expiresAt: time.Now().Add(permissionCacheTTL)}
+	return response.UserIDs, nil
+}
+
+// LookupUsernames resolves usernames to user IDs, keyed by lower-cased username.
+// Unknown usernames are missing from the result.
+func (c *StakeholdersClient) LookupUsernames(ctx context.Context, authHeader string, usernames []string) (map[string]int, error) {
+	var response struct {
+		Users []struct {
+			ID       int    `json:"id"`
+			Username string `json:"username"`
+		} `json:"users"`
+	}
+	body := map[string]interface{}{"usernames": usernames}
+	if err := c.doJSON(ctx, http.MethodPost, "/users/lookup", authHeader, body, &response); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int, len(response.Users))
+	for _, user := range response.Users {
+		ids[strings.ToLower(user.Username)] = user.ID
+	}
+	return ids, nil
+}
+
+// BlockUser blocks an account through the admin API, authHeader must be an admin's
+func (c *StakeholdersClient) BlockUser(ctx context.Context, authHeader string, userID int, reason string) error {
+	var response struct {
+		IsBlocked bool `json:"is_blocked"`
+	}
+	body := map[string]interface{}{"reason": reason}
+	path := fmt.Sprintf("/admin/users/%d/block", userID)
+	if err := c.doJSON(ctx, http.MethodPost, path, authHeader, body, &response); err != nil {
+		return err
+	}
+	if !response.IsBlocked {
+		return fmt.Errorf("user %d was not blocked", userID)
+	}
+	return nil
+}
+
+// pruneLocked drops expired entries, or everything if the cache is still full
+func (c *StakeholdersClient) pruneLocked() {
+	now := time.Now()
+	for pair, entry := range c.cache {
+		if now.After(entry.expiresAt) {
+			delete(c.cache, pair)
+		}
+	}
+	if len(c.cache) >= maxCacheEntries {
+		c.cache = make(map[CommentPair]cacheEntry)
+	}
+}
+
+// statusError is a non-2xx response, only 5xx ones are retried
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("stakeholders-service responded %d: %s", e.status, e.body)
+}
+
+func (c *StakeholdersClient) doJSON(ctx context.Context, method, path, authHeader string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryBackoff * time.Duration(1<<(attempt-2))):
+			}
+		}
+
+		lastErr = c.attempt(ctx, method, path, authHeader, payload, out)
+		if lastErr == nil {
+			return nil
+		}
+		if se, ok := lastErr.(*statusError); ok && se.status < 500 {
+			return lastErr
+		}
+		log.Printf("stakeholders-service %s %s attempt %d failed: %v", method, path, attempt, lastErr)
+	}
+	return lastErr
+}
+
+func (c *StakeholdersClient) attempt(ctx context.Context, method, path, authHeader string, payload []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{status: resp.StatusCode, body: string(data)}
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unexpected response format: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"content-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Comments stay embedded in the blog document but have their own IDs, and
+// comment_count is kept next to them so listings can leave the array out.
+// It counts the comments readers see, moderator-hidden ones are left out.
+// Replies point at their parent and are nested at most maxCommentDepth deep.
+
+const (
+	maxCommentDepth = 4
+	// Further mentions in one comment are not resolved
+	maxMentions = 10
+)
+
+// Same characters and length stakeholders-service allows in usernames
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_.\-]{3,50})`)
+
+// CommentNode is a comment with its replies for ?format=tree
+type CommentNode struct {
+	Comment
+	Replies []*CommentNode `json:"replies"`
+}
+
+// visibleCommentCount recounts comment_count in a pipeline update
+var visibleCommentCount = bson.M{"$size": bson.M{"$filter": bson.M{
+	"input": "$comments",
+	"cond":  bson.M{"$eq": bson.A{bson.M{"$type": "$$this.hidden_at"}, "missing"}},
+}}}
+
+// blogListProjection leaves comments out of blog listings, GET /blogs/:id/comments
+// pages them. Listings show the excerpt, so the rendered HTML is left out too.
+var blogListProjection = bson.M{"comments": 0, "description_html": 0}
+
+type UpdateCommentRequest struct {
+	Text string `json:"text" binding:"required,min=1"`
+}
+
+// commentParams parses :id and :commentId, writing 400 on failure
+func commentParams(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	blogID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blog ID"})
+		return blogID, primitive.NilObjectID, false
+	}
+	commentID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return blogID, commentID, false
+	}
+	return blogID, commentID, true
+}
+
+// replyParent validates parent_id of a new comment and returns it with the
+// reply's depth, nil and 0 for a top-level comment
+func replyParent(c *gin.Context, blog *Blog, parentIDStr string) (*primitive.ObjectID, int, bool) {
+	if parentIDStr == "" {
+		return nil, 0, true
+	}
+	parentID, err := primitive.ObjectIDFromHex(parentIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent comment ID"})
+		return nil, 0, false
+	}
+	parent := findComment(blog, parentID)
+	if parent == nil || parent.HiddenAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+		return nil, 0, false
+	}
+	if parent.Depth >= maxCommentDepth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Replies cannot be nested this deep, reply to an earlier comment"})
+		return nil, 0, false
+	}
+	return &parentID, parent.Depth + 1, true
+}
+
+// resolveMentions returns the IDs of the users @mentioned in text. Mentions
+// are not essential, so a failing lookup only leaves them out.
+func resolveMentions(ctx context.Context, c *gin.Context, text string) []int {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if len(username) < 3 || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	ids, err := stakeholdersClient.LookupUsernames(ctx, c.GetHeader("Authorization"), usernames)
+	if err != nil {
+		log.Printf("Error resolving mentions: %v", err)
+		return nil
+	}
+
+	var mentions []int
+	for _, username := range usernames {
+		if id, ok := ids[username]; ok {
+			mentions = append(mentions, id)
+		}
+	}
+	return mentions
+}
+
+// commentTree nests comments under their parents. Replies whose parent is
+// gone are shown at the top level rather than dropped.
+func commentTree(comments []Comment) []*CommentNode {
+	nodes := make(map[primitive.ObjectID]*CommentNode, len(comments))
+	for _, comment := range comments {
+		nodes[comment.ID] = &CommentNode{Comment: comment, Replies: []*CommentNode{}}
+	}
+
+	roots := []*CommentNode{}
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if comment.ParentID != nil {
+			if parent, ok := nodes[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// commentSubtree returns commentID and the IDs of all replies below it
+func commentSubtree(comments []Comment, commentID primitive.ObjectID) []primitive.ObjectID {
+	ids := []primitive.ObjectID{commentID}
+	for i := 0; i < len(ids); i++ {
+		for _, comment := range comments {
+			if comment.ParentID != nil && *comment.ParentID == ids[i] {
+				ids = append(ids, comment.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// visibleComments leaves out comments hidden by a moderator. Their replies
+// stay and are shown at the top level of a tree.
+func visibleComments(comments []Comment) []Comment {
+	visible := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		if comment.HiddenAt == nil {
+			visible = append(visible, comment)
+		}
+	}
+	return visible
+}
+
+func findComment(blog *Blog, commentID primitive.ObjectID) *Comment {
+	for i := range blog.Comments {
+		if blog.Comments[i].ID == commentID {
+			return &blog.Comments[i]
+		}
+	}
+	return nil
+}
+
+// GET /blogs/:id/comments?page=&limit=&format= - comments of a blog, oldest
+// first. format=flat (default) pages all comments, each with its parent_id and
+// depth. format=tree pages top-level comments with their replies nested.
+func getComments(c *gin.Context) {
+	blogID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blog ID"})
+		return
+	}
+	format := c.DefaultQuery("format", "flat")
+	if format != "flat" && format != "tree" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, use flat or tree"})
+		return
+	}
+	page := parsePagination(c, 20, 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var blog Blog
+	err = blogsCollection.FindOne(
+		ctx,
+		bson.M{"$and": bson.A{bson.M{"_id": blogID}, visibleBlogsFilter(c)}},
+		options.FindOne().SetProjection(bson.M{"comments": 1}),
+	).Decode(&blog)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return
+		}
+		log.Printf("Error loading comments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	comments := visibleComments(blog.Comments)
+	if format == "tree" {
+		roots := commentTree(comments)
+		c.JSON(http.StatusOK, gin.H{
+			"comments":   pageOf(roots, page),
+			"total":      len(comments),
+			"pagination": page.Response(int64(len(roots))),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments":   pageOf(comments, page),
+		"pagination": page.Response(int64(len(comments))),
+	})
+}
+
+// pageOf cuts the requested page out of items
+func pageOf[T any](items []T, page pagination) []T {
+	start := page.Offset()
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + page.Limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// PUT /blogs/:id/comments/:commentId - edit a comment, only its author may
+func updateComment(c *gin.Context) {
+	blogID, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	blog, ok := findVisibleBlog(ctx, c, blogID)
+	if !ok || !requireOpenBlog(c, blog) {
+		return
+	}
+
+	comment := findComment(blog, commentID)
+	if comment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	userID, _ := auth.UserID(c)
+	if comment.UserID != userID {
+		auth.Forbidden(c, "You can only edit your own comments")
+		return
+	}
+
+	comment.Text = req.Text
+	comment.Mentions = resolveMentions(ctx, c, req.Text)
+	comment.UpdatedAt = time.Now()
+
+	result, err := blogsCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": blogID, "comments._id": commentID},
+		bson.M{"$set": bson.M{
+			"comments.$.text":       comment.Text,
+			"comments.$.mentions":   comment.Mentions,
+			"comments.$.updated_at": comment.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		log.Printf("Error updating comment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment updated successfully",
+		"comment": comment,
+	})
+}
+
+// DELETE /blogs/:id/comments/:commentId - remove a comment, allowed for its
+// author, the blog author and admins. Its replies may belong to others, so
+// they stay and move up one level to the removed comment's parent.
+func deleteComment(c *gin.Context) {
+	blogID, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	blog, ok := findVisibleBlog(ctx, c, blogID)
+	if !ok {
+		return
+	}
+
+	comment := findComment(blog, commentID)
+	if comment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	userID, _ := auth.UserID(c)
+	if comment.UserID != userID && !auth.IsOwnerOrAdmin(c, blog.AuthorID) {
+		auth.Forbidden(c, "You can only delete your own comments or comments on your blogs")
+		return
+	}
+
+	// Direct replies take over the removed comment's parent, or become top
+	// level comments when it had none
+	var reparent interface{} = bson.M{"$unsetField": bson.M{"field": "parent_id", "input": "$$comment"}}
+	if comment.ParentID != nil {
+		reparent = bson.M{"$mergeObjects": bson.A{"$$comment", bson.M{"parent_id": *comment.ParentID}}}
+	}
+	replies := commentSubtree(blog.Comments, commentID)[1:]
+
+	// The counter is recounted from the array in the same write, so it stays
+	// right when replies are added or removed concurrently
+	result, err := blogsCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": blogID, "comments._id": commentID},
+		bson.A{
+			bson.M{"$set": bson.M{"comments": bson.M{"$map": bson.M{
+				"input": bson.M{"$filter": bson.M{
+					"input": "$comments",
+					"cond":  bson.M{"$ne": bson.A{"$$this._id", commentID}},
+				}},
+				"as": "comment",
+				"in": bson.M{"$cond": bson.A{
+					bson.M{"$in": bson.A{"$$comment._id", replies}},
+					bson.M{"$mergeObjects": bson.A{
+						bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$comment.parent_id", commentID}}, reparent, "$$comment"}},
+						bson.M{"depth": bson.M{"$subtract": bson.A{"$$comment.depth", 1}}},
+					}},
+					"$$comment",
+				}},
+			}}}},
+			bson.M{"$set": bson.M{"comment_count": visibleCommentCount}},
+		},
+	)
+	if err != nil {
+		log.Printf("Error deleting comment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Comment deleted successfully",
+		"deleted_count":      1,
+		"replies_reparented": len(replies),
+	})
+}
+
+// backfillCommentIDs gives comments written before they had IDs one and sets
+// comment_count on blogs that lack it
+func backfillCommentIDs(ctx context.Context) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"comment_count": bson.M{"$exists": false}},
+		bson.M{"comments": bson.M{"$elemMatch": bson.M{"_id": bson.M{"$exists": false}}}},
+	}}
+	cursor, err := blogsCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"comments": 1}))
+	if err != nil {
+		log.Printf("Error finding blogs to backfill comments: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var updates []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var blog Blog
+		if err := cursor.Decode(&blog); err != nil {
+			log.Printf("Error decoding blog for comment backfill: %v", err)
+			continue
+		}
+		for i := range blog.Comments {
+			if blog.Comments[i].ID.IsZero() {
+				blog.Comments[i].ID = primitive.NewObjectID()
+			}
+		}
+		if blog.Comments == nil {
+			blog.Comments = []Comment{}
+		}
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": blog.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"comments": blog.Comments, "comment_count": len(visibleComments(blog.Comments))}}))
+	}
+	if len(updates) == 0 {
+		return
+	}
+
+	if _, err := blogsCollection.BulkWrite(ctx, updates); err != nil {
+		log.Printf("Error backfilling comment IDs: %v", err)
+		return
+	}
+	log.Printf("Backfilled comment IDs and counts of %d blogs", len(updates))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"content-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// feedCursor points at the last blog of a page. Blogs are ordered by
+// created_at and then _id, so equal timestamps never skip or repeat entries.
+type feedCursor struct {
+	CreatedAt time.Time          `json:"t"`
+	ID        primitive.ObjectID `json:"id"`
+}
+
+func encodeFeedCursor(blog Blog) string {
+	data, _ := json.Marshal(feedCursor{CreatedAt: blog.CreatedAt, ID: blog.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeFeedCursor(value string) (*feedCursor, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false
+	}
+	var cursor feedCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID.IsZero() {
+		return nil, false
+	}
+	return &cursor, true
+}
+
+// GET /blogs/feed?cursor=&limit= - blogs of followed authors and the caller's own, newest first
+func getBlogFeed(c *gin.Context) {
+	userID, _ := auth.UserID(c)
+
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	followingIDs, err := stakeholdersClient.FollowingIDs(ctx, c.GetHeader("Authorization"), userID)
+	if err != nil {
+		log.Printf("Error loading followed users of %d: %v", userID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to load followed users"})
+		return
+	}
+
+	// Copied, the cached slice is shared between requests
+	authorIDs := append([]int{userID}, followingIDs...)
+	filter := bson.M{"$and": bson.A{
+		bson.M{"author_id": bson.M{"$in": authorIDs}},
+		visibleBlogsFilter(c),
+	}}
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, ok := decodeFeedCursor(cursorStr)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		filter["$or"] = bson.A{
+			bson.M{"created_at": bson.M{"$lt": cursor.CreatedAt}},
+			bson.M{"created_at": cursor.CreatedAt, "_id": bson.M{"$lt": cursor.ID}},
+		}
+	}
+
+	// One extra blog tells whether another page exists
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1)).
+		SetProjection(blogListProjection)
+
+	cursor, err := blogsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Printf("Error finding feed blogs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	blogs := []Blog{}
+	if err = cursor.All(ctx, &blogs); err != nil {
+		log.Printf("Error decoding feed blogs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	hasMore := len(blogs) > limit
+	var nextCursor string
+	if hasMore {
+		blogs = blogs[:limit]
+		nextCursor = encodeFeedCursor(blogs[limit-1])
+	}
+
+	setCommentPermissions(c, blogs)
+	setLikedFlags(c, blogRefs(blogs))
+
+	c.JSON(http.StatusOK, gin.H{
+		"blogs":       blogs,
+		"next_cursor": nextCursor,
+		"has_more":    hasMore,
+		"limit":       limit,
+	})
+}
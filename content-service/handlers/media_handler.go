@@ -0,0 +1,245 @@
+// content-service/handlers/media_handler.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"content-service/auth"
+	"content-service/media"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MediaHandler accepts image uploads for profiles, blogs, keypoints and reviews.
+// The returned URLs are stored in those documents as plain strings.
+type MediaHandler struct {
+	DB      *mongo.Database
+	Storage media.Storage
+}
+
+type Media struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OwnerID      int                `json:"owner_id" bson:"owner_id"`
+	Purpose      string             `json:"purpose" bson:"purpose"`
+	Key          string             `json:"-" bson:"key"`
+	ThumbnailKey string             `json:"-" bson:"thumbnail_key"`
+	URL          string             `json:"url" bson:"-"`
+	ThumbnailURL string             `json:"thumbnail_url" bson:"-"`
+	ContentType  string             `json:"content_type" bson:"content_type"`
+	Size         int                `json:"size" bson:"size"`
+	Width        int                `json:"width" bson:"width"`
+	Height       int                `json:"height" bson:"height"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Where an upload is going to be used, kept for housekeeping
+var mediaPurposes = map[string]bool{
+	"profile":  true,
+	"blog":     true,
+	"keypoint": true,
+	"review":   true,
+}
+
+func NewMediaHandler(db *mongo.Database, storage media.Storage) *MediaHandler {
+	return &MediaHandler{DB: db, Storage: storage}
+}
+
+func (h *MediaHandler) withURLs(m *Media) *Media {
+	m.URL = h.Storage.URL(m.Key)
+	m.ThumbnailURL = h.Storage.URL(m.ThumbnailKey)
+	return m
+}
+
+// POST /media - upload an image as multipart field "file", optional form field
+// "purpose" (profile, blog, keypoint or review)
+func (h *MediaHandler) UploadMedia(c *gin.Context) {
+	userID, _ := auth.UserID(c)
+
+	purpose := c.DefaultPostForm("purpose", "blog")
+	if !mediaPurposes[purpose] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purpose, use profile, blog, keypoint or review"})
+		return
+	}
+
+	// Limit the whole body, the multipart overhead is small
+	const maxBody = media.MaxUploadBytes + 64<<10
+	if c.Request.ContentLength > maxBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": media.ErrTooLarge.Error()})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": media.ErrTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Multipart field 'file' is required"})
+		return
+	}
+	if fileHeader.Size > media.MaxUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": media.ErrTooLarge.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxUploadBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	img, err := media.ProcessImage(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, media.ErrTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		} else if errors.Is(err, media.ErrUnsupportedType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := h.Storage.Put(ctx, img.Key, img.ContentType, img.Data); err != nil {
+		log.Printf("Error storing media %s: %v", img.Key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+	if err := h.Storage.Put(ctx, img.ThumbnailKey, img.ThumbnailContentType, img.Thumbnail); err != nil {
+		log.Printf("Error storing thumbnail %s: %v", img.ThumbnailKey, err)
+		h.removeFiles(ctx, img.Key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	record := Media{
+		OwnerID:      userID,
+		Purpose:      purpose,
+		Key:          img.Key,
+		ThumbnailKey: img.ThumbnailKey,
+		ContentType:  img.ContentType,
+		Size:         len(img.Data),
+		Width:        img.Width,
+		Height:       img.Height,
+		CreatedAt:    time.Now(),
+	}
+
+	result, err := h.DB.Collection("media").InsertOne(ctx, record)
+	if err != nil {
+		log.Printf("Error saving media record: %v", err)
+		h.removeFiles(ctx, img.Key, img.ThumbnailKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	record.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, h.withURLs(&record))
+}
+
+// GET /media/:id - metadata and URLs of an upload
+func (h *MediaHandler) GetMedia(c *gin.Context) {
+	record, ok := h.findMedia(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.withURLs(record))
+}
+
+// DELETE /media/:id - delete an upload, owner or admin only. Documents that
+// still reference the URL are not touched.
+func (h *MediaHandler) DeleteMedia(c *gin.Context) {
+	record, ok := h.findMedia(c)
+	if !ok {
+		return
+	}
+	if !auth.IsOwnerOrAdmin(c, record.OwnerID) {
+		auth.Forbidden(c, "You can only delete your own uploads")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := h.DB.Collection("media").DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+		log.Printf("Error deleting media record: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	h.removeFiles(ctx, record.Key, record.ThumbnailKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
+
+// GET /media/files/*key - serve files of the local storage backend. Keys are
+// random and never reused, so clients may cache them indefinitely.
+func (h *MediaHandler) ServeFile(c *gin.Context) {
+	local, ok := h.Storage.(*media.LocalStorage)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	path, err := local.Path(c.Param("key"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(path)
+}
+
+func (h *MediaHandler) findMedia(c *gin.Context) (*Media, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return nil, false
+	}
+
+	var record Media
+	err = h.DB.Collection("media").FindOne(context.TODO(), bson.M{"_id": objectID}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error fetching media: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+	return &record, true
+}
+
+// removeFiles deletes stored files, failures only leave orphans behind so they are logged
+func (h *MediaHandler) removeFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := h.Storage.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting media file %s: %v", key, err)
+		}
+	}
+}
@@ -5,9 +5,10 @@ import (
 	"context"
 	"math"
 	"net/http"
-	"strconv"
 	"time"
 
+	"content-service/auth"
+
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -68,12 +69,7 @@ func NewTourExecutionHandler(db *mongo.Database) *TourExecutionHandler {
 
 // POST /tours/start - start tour execution
 func (h *TourExecutionHandler) StartTour(c *gin.Context) {
-	// Get user ID from header (mock auth)
-	userIDStr := c.GetHeader("X-User-ID")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID <= 0 {
-		userID = 1 // Default mock user
-	}
+	userID := getUserID(c)
 
 	var req StartTourRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -122,9 +118,7 @@ func (h *TourExecutionHandler) StartTour(c *gin.Context) {
 		canStart = true
 	} else if tour.Status == "draft" {
 		// Only author can start draft tours
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": "This tour is in draft mode and can only be started by the author",
-		})
+		auth.Forbidden(c, "This tour is in draft mode and can only be started by the author")
 		return
 	} else if tour.Status == "published" || tour.Status == "archived" {
 		// For published/archived tours, check if purchase is required
@@ -158,9 +152,7 @@ func (h *TourExecutionHandler) StartTour(c *gin.Context) {
 	}
 
 	if !canStart {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": "You are not authorized to start this tour",
-		})
+		auth.Forbidden(c, "You are not authorized to start this tour")
 		return
 	}
 
@@ -235,16 +227,12 @@ func (h *TourExecutionHandler) StartTour(c *gin.Context) {
 
 // POST /tours/check-keypoints - check if user is near any keypoint
 func (h *TourExecutionHandler) CheckKeypoints(c *gin.Context) {
-	userIDStr := c.GetHeader("X-User-ID")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID <= 0 {
-		userID = 1 // Default mock user
-	}
+	userID := getUserID(c)
 
 	// Get user's active tour execution
 	executionsCollection := h.DB.Collection("tour_executions")
 	var execution TourExecution
-	err = executionsCollection.FindOne(context.TODO(), bson.M{
+	err := executionsCollection.FindOne(context.TODO(), bson.M{
 		"user_id": userID,
 		"status":  "active",
 	}).Decode(&execution)
@@ -371,11 +359,7 @@ func (h *TourExecutionHandler) CheckKeypoints(c *gin.Context) {
 
 // PUT /tours/:executionId/abandon - abandon tour
 func (h *TourExecutionHandler) AbandonTour(c *gin.Context) {
-	userIDStr := c.GetHeader("X-User-ID")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID <= 0 {
-		userID = 1 // Default mock user
-	}
+	userID := getUserID(c)
 
 	executionIDStr := c.Param("executionId")
 	executionID, err := primitive.ObjectIDFromHex(executionIDStr)
@@ -427,11 +411,7 @@ func (h *TourExecutionHandler) AbandonTour(c *gin.Context) {
 
 // GET /tours/executions - get user's tour executions
 func (h *TourExecutionHandler) GetUserExecutions(c *gin.Context) {
-	userIDStr := c.GetHeader("X-User-ID")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID <= 0 {
-		userID = 1 // Default mock user
-	}
+	userID := getUserID(c)
 
 	executionsCollection := h.DB.Collection("tour_executions")
 
@@ -6,6 +6,7 @@ import (
     "strconv"
     "time"
     "errors"
+    "content-service/auth"
     "content-service/models"
     "github.com/gin-gonic/gin"
     "go.mongodb.org/mongo-driver/bson"
@@ -48,8 +49,7 @@ func (h *TourHandler) GetTours(c *gin.Context) {
         filter["status"] = status
     } else {
         // Default: only show published tours for general browsing
-        userIDStr := c.GetHeader("X-User-ID")
-        if userIDStr == "" {
+        if _, ok := auth.UserID(c); !ok {
             filter["status"] = "published"
         }
     }
@@ -67,17 +67,18 @@ func (h *TourHandler) GetTours(c *gin.Context) {
         return
     }
 
+    for i := range tours {
+        tours[i].Reviews = visibleReviews(tours[i].Reviews)
+    }
+
     // For published tours, check purchase status and limit keypoints
-    userIDStr := c.GetHeader("X-User-ID")
-    if userIDStr != "" {
-        userID, err := strconv.Atoi(userIDStr)
-        if err == nil {
-            for i, tour := range tours {
-                if tour.Status == "published" {
-                    hasPurchased := h.checkTourPurchase(userID, tour.ID.Hex())
-                    if !hasPurchased && len(tour.Keypoints) > 0 {
-                        tours[i].Keypoints = tour.Keypoints[:1] // Only first keypoint
-                    }
+    if _, ok := auth.UserID(c); ok {
+        authHeader := c.GetHeader("Authorization")
+        for i, tour := range tours {
+            if tour.Status == "published" {
+                hasPurchased := h.checkTourPurchase(authHeader, tour.ID.Hex())
+                if !hasPurchased && len(tour.Keypoints) > 0 {
+                    tours[i].Keypoints = tour.Keypoints[:1] // Only first keypoint
                 }
             }
         }
@@ -93,7 +94,20 @@ func (h *TourHandler) GetTours(c *gin.Context) {
     c.JSON(http.StatusOK, gin.H{"tours": tours})
 }
 
-func (h *TourHandler) checkTourPurchase(userID int, tourID string) bool {
+// visibleReviews leaves out reviews hidden by a moderator
+func visibleReviews(reviews []models.Review) []models.Review {
+    visible := make([]models.Review, 0, len(reviews))
+    for _, review := range reviews {
+        if review.HiddenAt == nil {
+            visible = append(visible, review)
+        }
+    }
+    return visible
+}
+
+// checkTourPurchase forwards the caller's bearer token so commerce-service
+// checks the purchase for the same user
+func (h *TourHandler) checkTourPurchase(authHeader string, tourID string) bool {
     // Call commerce service to check purchase
     commerceURL := "http://commerce-service:8083/purchase/check/" + tourID
     
@@ -103,7 +117,7 @@ func (h *TourHandler) checkTourPurchase(userID int, tourID string) bool {
         return false
     }
     
-    req.Header.Set("X-User-ID", strconv.Itoa(userID))
+    req.Header.Set("Authorization", authHeader)
     
     resp, err := client.Do(req)
     if err != nil {
@@ -134,8 +148,8 @@ func (h *TourHandler) checkTourPurchase(userID int, tourID string) bool {
 // GET /tours/:id - get tour by ID
 func (h *TourHandler) GetTourByID(c *gin.Context) {
     idStr := c.Param("id")
-    userIDStr := c.GetHeader("X-User-ID")
-    
+    userID, authenticated := auth.UserID(c)
+
     objectID, err := primitive.ObjectIDFromHex(idStr)
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tour ID"})
@@ -153,11 +167,10 @@ func (h *TourHandler) GetTourByID(c *gin.Context) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
         return
     }
+    tour.Reviews = visibleReviews(tour.Reviews)
 
     // Only limit keypoints for non-authors on published tours
-    if userIDStr != "" {
-        userID, _ := strconv.Atoi(userIDStr)
-        
+    if authenticated {
         // If user is the author, always show all keypoints
         if tour.AuthorID == userID {
             c.JSON(http.StatusOK, gin.H{"tour": tour})
@@ -294,7 +307,7 @@ func (h *TourHandler) UpdateTour(c *gin.Context) {
     }
 
     if tour.AuthorID != userID {
-        c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own tours"})
+        auth.Forbidden(c, "You can only update your own tours")
         return
     }
 
@@ -401,7 +414,7 @@ func (h *TourHandler) AddKeypoint(c *gin.Context) {
     }
 
     if tour.AuthorID != userID {
-        c.JSON(http.StatusForbidden, gin.H{"error": "You can only add keypoints to your own tours"})
+        auth.Forbidden(c, "You can only add keypoints to your own tours")
         return
     }
 
@@ -474,7 +487,7 @@ func (h *TourHandler) UpdateKeypoint(c *gin.Context) {
     }
 
     if tour.AuthorID != userID {
-        c.JSON(http.StatusForbidden, gin.H{"error": "You can only update keypoints on your own tours"})
+        auth.Forbidden(c, "You can only update keypoints on your own tours")
         return
     }
 
@@ -551,7 +564,7 @@ func (h *TourHandler) RemoveKeypoint(c *gin.Context) {
     }
 
     if tour.AuthorID != userID {
-        c.JSON(http.StatusForbidden, gin.H{"error": "You can only remove keypoints from your own tours"})
+        auth.Forbidden(c, "You can only remove keypoints from your own tours")
         return
     }
 
@@ -590,13 +603,9 @@ func (h *TourHandler) RemoveKeypoint(c *gin.Context) {
 }
 
 // Helper function to get user ID from context
+// Routes using it sit behind auth.Middleware, so the id is always set
 func getUserID(c *gin.Context) int {
-    if userIDStr := c.GetHeader("X-User-ID"); userIDStr != "" {
-        if id, err := strconv.Atoi(userIDStr); err == nil {
-            return id
-        }
-    }
-    return 1 // Default mock user
+    return c.GetInt(auth.ContextUserID)
 }
 
 func (h *TourHandler) ClearAllKeypoints(c *gin.Context) {
@@ -623,7 +632,7 @@ func (h *TourHandler) ClearAllKeypoints(c *gin.Context) {
     }
 
     if tour.AuthorID != userID {
-        c.JSON(http.StatusForbidden, gin.H{"error": "You can only modify your own tours"})
+        auth.Forbidden(c, "You can only modify your own tours")
         return
     }
 
@@ -681,7 +690,7 @@ func (h *TourHandler) AddTransportTime(c *gin.Context) {
     }
 
     if tour.AuthorID != userID {
-        c.JSON(http.StatusForbidden, gin.H{"error": "You can only modify your own tours"})
+        auth.Forbidden(c, "You can only modify your own tours")
         return
     }
 
@@ -778,7 +787,7 @@ func (h *TourHandler) RemoveTransportTime(c *gin.Context) {
     }
 
     if tour.AuthorID != userID {
-        c.JSON(http.StatusForbidden, gin.H{"error": "You can only modify your own tours"})
+        auth.Forbidden(c, "You can only modify your own tours")
         return
     }
 
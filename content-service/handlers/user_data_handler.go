@@ -0,0 +1,385 @@
+// content-service/handlers/user_data_handler.go
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"content-service/media"
+	"content-service/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UserDataHandler serves the personal data export and erasure that
+// stakeholders-service orchestrates across all services
+type UserDataHandler struct {
+	DB      *mongo.Database
+	Storage media.Storage
+}
+
+func NewUserDataHandler(db *mongo.Database, storage media.Storage) *UserDataHandler {
+	return &UserDataHandler{DB: db, Storage: storage}
+}
+
+func userIDParam(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// ownEntries projects a document's embedded array down to the user's entries
+func ownEntries(field string, userID int, extra bson.M) bson.M {
+	projection := bson.M{
+		field: bson.M{"$filter": bson.M{
+			"input": "$" + field,
+			"cond":  bson.M{"$eq": bson.A{"$$this.user_id", userID}},
+		}},
+	}
+	for key, value := range extra {
+		projection[key] = value
+	}
+	return projection
+}
+
+func findAll(ctx context.Context, collection *mongo.Collection, pipeline interface{}, out interface{}) error {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, out)
+}
+
+// GET /internal/users/:id/export - everything content-service stores about the user
+func (h *UserDataHandler) ExportUserData(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	blogs := h.DB.Collection("blogs")
+	tours := h.DB.Collection("tours")
+
+	var (
+		ownBlogs   = []bson.M{}
+		comments   = []bson.M{}
+		likedBlogs = []bson.M{}
+		ownTours   = []models.Tour{}
+		reviews    = []bson.M{}
+		executions = []TourExecution{}
+		positions  = []Position{}
+		uploads    = []Media{}
+		reports    = []bson.M{}
+	)
+
+	queries := []struct {
+		name       string
+		collection *mongo.Collection
+		pipeline   bson.A
+		out        interface{}
+	}{
+		{"blogs", blogs, bson.A{bson.M{"$match": bson.M{"author_id": userID}}}, &ownBlogs},
+		{"comments", blogs, bson.A{
+			bson.M{"$match": bson.M{"comments.user_id": userID}},
+			bson.M{"$project": ownEntries("comments", userID, bson.M{"title": 1})},
+		}, &comments},
+		{"likes", h.DB.Collection("blog_likes"), bson.A{
+			bson.M{"$match": bson.M{"user_id": userID}},
+			bson.M{"$lookup": bson.M{"from": "blogs", "localField": "blog_id", "foreignField": "_id", "as": "blog"}},
+			bson.M{"$project": bson.M{"_id": 0, "blog_id": 1, "created_at": 1, "title": bson.M{"$first": "$blog.title"}}},
+		}, &likedBlogs},
+		{"tours", tours, bson.A{bson.M{"$match": bson.M{"author_id": userID}}}, &ownTours},
+		{"reviews", tours, bson.A{
+			bson.M{"$match": bson.M{"reviews.user_id": userID}},
+			bson.M{"$project": ownEntries("reviews", userID, bson.M{"name": 1})},
+		}, &reviews},
+		{"tour_executions", h.DB.Collection("tour_executions"), bson.A{bson.M{"$match": bson.M{"user_id": userID}}}, &executions},
+		{"positions", h.DB.Collection("positions"), bson.A{bson.M{"$match": bson.M{"user_id": userID}}}, &positions},
+		{"media", h.DB.Collection("media"), bson.A{bson.M{"$match": bson.M{"owner_id": userID}}}, &uploads},
+		{"reports", h.DB.Collection("reports"), bson.A{
+			bson.M{"$match": bson.M{"reporter_id": userID}},
+			bson.M{"$project": bson.M{"target_key": 0}},
+		}, &reports},
+	}
+
+	for _, q := range queries {
+		if err := findAll(ctx, q.collection, q.pipeline, q.out); err != nil {
+			log.Printf("Error exporting %s of user %d: %v", q.name, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+	}
+	mediaHandler := MediaHandler{Storage: h.Storage}
+	for i := range uploads {
+		mediaHandler.withURLs(&uploads[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service":         "content-service",
+		"user_id":         userID,
+		"exported_at":     time.Now(),
+		"blogs":           ownBlogs,
+		"blog_comments":   comments,
+		"liked_blogs":     likedBlogs,
+		"tours":           ownTours,
+		"tour_reviews":    reviews,
+		"tour_executions": executions,
+		"positions":       positions,
+		"media":           uploads,
+		"reports":         reports,
+	})
+}
+
+// DELETE /internal/users/:id/data - erase the user's content. Blogs, comments,
+// likes, reviews, executions, positions and uploaded media are removed, as are
+// reports filed by the user or about their content. Draft tours are deleted,
+// other tours are archived so tourists who bought them keep access. Replies
+// to the user's comments stay and move up to the nearest remaining comment.
+func (h *UserDataHandler) DeleteUserData(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	blogs := h.DB.Collection("blogs")
+	tours := h.DB.Collection("tours")
+	now := time.Now()
+
+	summary := gin.H{}
+	fail := func(step string, err error) {
+		log.Printf("Error erasing %s of user %d: %v", step, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user data", "step": step, "done": summary})
+	}
+
+	// Files go first, their records are needed to find them
+	var uploads []Media
+	if err := findAll(ctx, h.DB.Collection("media"), bson.A{bson.M{"$match": bson.M{"owner_id": userID}}}, &uploads); err != nil {
+		fail("media", err)
+		return
+	}
+	for _, m := range uploads {
+		for _, key := range []string{m.Key, m.ThumbnailKey} {
+			if err := h.Storage.Delete(ctx, key); err != nil {
+				fail("media_files", err)
+				return
+			}
+		}
+	}
+	summary["media_files_deleted"] = len(uploads)
+
+	// Likes go first: the user's likes lower the counts of the blogs they
+	// liked, likes on the user's own blogs are dropped together with them.
+	// Each like is deleted before its count is lowered, so a retry after a
+	// partial failure does not lower a count twice.
+	likes := h.DB.Collection("blog_likes")
+	var liked []struct {
+		ID     primitive.ObjectID `bson:"_id"`
+		BlogID primitive.ObjectID `bson:"blog_id"`
+	}
+	if err := findAll(ctx, likes, bson.A{bson.M{"$match": bson.M{"user_id": userID}}}, &liked); err != nil {
+		fail("likes", err)
+		return
+	}
+	var unliked int64
+	for _, like := range liked {
+		result, err := likes.DeleteOne(ctx, bson.M{"_id": like.ID})
+		if err != nil {
+			fail("likes", err)
+			return
+		}
+		if result.DeletedCount == 0 {
+			continue
+		}
+		if _, err := blogs.UpdateOne(ctx, bson.M{"_id": like.BlogID}, bson.M{"$inc": bson.M{"like_count": -1}}); err != nil {
+			fail("like_counts", err)
+			return
+		}
+		unliked++
+	}
+	summary["likes_given_deleted"] = unliked
+
+	removedFrom, err := eraseComments(ctx, blogs, userID)
+	if err != nil {
+		fail("comments", err)
+		return
+	}
+	summary["comments_removed_from_blogs"] = removedFrom
+
+	var ownBlogs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := findAll(ctx, blogs, bson.A{
+		bson.M{"$match": bson.M{"author_id": userID}},
+		bson.M{"$project": bson.M{"_id": 1}},
+	}, &ownBlogs); err != nil {
+		fail("blogs", err)
+		return
+	}
+	ownBlogIDs := make([]primitive.ObjectID, len(ownBlogs))
+	for i, blog := range ownBlogs {
+		ownBlogIDs[i] = blog.ID
+	}
+
+	deletes := []struct {
+		name       string
+		collection *mongo.Collection
+		filter     bson.M
+	}{
+		{"likes", likes, bson.M{"blog_id": bson.M{"$in": ownBlogIDs}}},
+		{"blogs", blogs, bson.M{"author_id": userID}},
+		{"draft_tours", tours, bson.M{"author_id": userID, "status": "draft"}},
+		{"tour_executions", h.DB.Collection("tour_executions"), bson.M{"user_id": userID}},
+		{"positions", h.DB.Collection("positions"), bson.M{"user_id": userID}},
+		{"media", h.DB.Collection("media"), bson.M{"owner_id": userID}},
+		{"reports", h.DB.Collection("reports"), bson.M{"$or": bson.A{
+			bson.M{"reporter_id": userID},
+			bson.M{"author_id": userID},
+		}}},
+	}
+	for _, d := range deletes {
+		result, err := d.collection.DeleteMany(ctx, d.filter)
+		if err != nil {
+			fail(d.name, err)
+			return
+		}
+		summary[d.name+"_deleted"] = result.DeletedCount
+	}
+
+	updates := []struct {
+		name       string
+		collection *mongo.Collection
+		filter     bson.M
+		update     interface{}
+	}{
+		{"reviews_removed_from_tours", tours, bson.M{"reviews.user_id": userID},
+			bson.M{"$pull": bson.M{"reviews": bson.M{"user_id": userID}}}},
+		{"tours_archived", tours, bson.M{"author_id": userID, "status": bson.M{"$ne": "archived"}},
+			bson.M{"$set": bson.M{"status": "archived", "archived_at": now, "updated_at": now}}},
+	}
+	for _, u := range updates {
+		result, err := u.collection.UpdateMany(ctx, u.filter, u.update)
+		if err != nil {
+			fail(u.name, err)
+			return
+		}
+		summary[u.name] = result.ModifiedCount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service": "content-service",
+		"user_id": userID,
+		"summary": summary,
+	})
+}
+
+// storedComment is the part of an embedded blog comment erasure rewrites,
+// the other fields pass through Rest unchanged
+type storedComment struct {
+	ID       primitive.ObjectID  `bson:"_id"`
+	UserID   int                 `bson:"user_id"`
+	ParentID *primitive.ObjectID `bson:"parent_id,omitempty"`
+	Depth    int                 `bson:"depth"`
+	HiddenAt *time.Time          `bson:"hidden_at,omitempty"`
+	Rest     bson.M              `bson:",inline"`
+}
+
+// eraseComments removes the user's comments from all blogs. Replies to them
+// are kept and move up to the nearest remaining ancestor, or to the top
+// level, so other users' comments never point at a missing parent. It returns
+// the number of blogs changed.
+func eraseComments(ctx context.Context, blogs *mongo.Collection, userID int) (int64, error) {
+	var changed int64
+	// A blog whose comments changed since it was read is read again
+	for attempt := 1; ; attempt++ {
+		var affected []struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Comments []storedComment    `bson:"comments"`
+		}
+		err := findAll(ctx, blogs, bson.A{
+			bson.M{"$match": bson.M{"comments.user_id": userID}},
+			bson.M{"$project": bson.M{"comments": 1}},
+		}, &affected)
+		if err != nil {
+			return changed, err
+		}
+
+		conflicts := 0
+		for _, blog := range affected {
+			kept, visible := withoutUserComments(blog.Comments, userID)
+			result, err := blogs.UpdateOne(ctx,
+				bson.M{"_id": blog.ID, "comments": bson.M{"$size": len(blog.Comments)}},
+				bson.M{"$set": bson.M{"comments": kept, "comment_count": visible}},
+			)
+			if err != nil {
+				return changed, err
+			}
+			if result.MatchedCount == 0 {
+				conflicts++
+				continue
+			}
+			changed++
+		}
+
+		if conflicts == 0 {
+			return changed, nil
+		}
+		if attempt == 3 {
+			return changed, fmt.Errorf("comments of %d blogs kept changing", conflicts)
+		}
+	}
+}
+
+// withoutUserComments drops the user's comments, re-parents the replies to
+// them and returns the rest with the number of comments that are not hidden
+func withoutUserComments(comments []storedComment, userID int) ([]storedComment, int) {
+	removed := make(map[primitive.ObjectID]bool)
+	parents := make(map[primitive.ObjectID]*primitive.ObjectID, len(comments))
+	for _, comment := range comments {
+		parents[comment.ID] = comment.ParentID
+		if comment.UserID == userID {
+			removed[comment.ID] = true
+		}
+	}
+
+	// Replies come after their parent, so depths are known when they are needed
+	depths := make(map[primitive.ObjectID]int, len(comments))
+	kept := []storedComment{}
+	visible := 0
+	for _, comment := range comments {
+		if removed[comment.ID] {
+			continue
+		}
+		parent := comment.ParentID
+		for parent != nil && removed[*parent] {
+			parent = parents[*parent]
+		}
+		comment.ParentID, comment.Depth = nil, 0
+		if parent != nil {
+			if depth, ok := depths[*parent]; ok {
+				comment.ParentID, comment.Depth = parent, depth+1
+			}
+		}
+		depths[comment.ID] = comment.Depth
+		kept = append(kept, comment)
+		if comment.HiddenAt == nil {
+			visible++
+		}
+	}
+	return kept, visible
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"content-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Likes are documents of their own in blog_likes, one per user and blog, so
+// a blog does not grow with its popularity. like_count on the blog is the
+// denormalized total and changes together with them, it is recounted from
+// blog_likes when that update fails.
+
+const (
+	// Only activity this recent counts for trending
+	trendingWindow = 7 * 24 * time.Hour
+	// A like or comment counts half as much after this long
+	trendingHalfLife = 24 * time.Hour
+	// A comment takes more effort than a like
+	trendingLikeWeight    = 1.0
+	trendingCommentWeight = 2.0
+	// Highest scores looked up, enough to fill a page after filtering
+	trendingCandidates = 200
+)
+
+var blogLikesCollection *mongo.Collection
+
+type BlogLike struct {
+	BlogID    primitive.ObjectID `json:"blog_id" bson:"blog_id"`
+	UserID    int                `json:"user_id" bson:"user_id"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// TrendingBlog is a blog with the decayed score it was ranked by
+type TrendingBlog struct {
+	Blog          `bson:",inline"`
+	TrendingScore float64 `json:"trending_score" bson:"-"`
+}
+
+// ensureBlogLikeIndexes creates the indexes likes rely on, the unique one
+// is what keeps a user from liking a blog twice
+func ensureBlogLikeIndexes(ctx context.Context) {
+	_, err := blogLikesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "blog_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "blog_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating blog like indexes: %v", err)
+	}
+}
+
+// migrateBlogLikes moves likes arrays of older blogs into blog_likes. The
+// time of those likes is unknown, the blog's creation time stands in for it.
+func migrateBlogLikes(ctx context.Context) {
+	cursor, err := blogsCollection.Find(ctx,
+		bson.M{"likes": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"likes": 1, "created_at": 1}),
+	)
+	if err != nil {
+		log.Printf("Error finding blogs with like arrays: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var blog struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			Likes     []int              `bson:"likes"`
+			CreatedAt time.Time          `bson:"created_at"`
+		}
+		if err := cursor.Decode(&blog); err != nil {
+			log.Printf("Error decoding blog likes: %v", err)
+			continue
+		}
+
+		if len(blog.Likes) > 0 {
+			likes := make([]interface{}, len(blog.Likes))
+			for i, userID := range blog.Likes {
+				likes[i] = BlogLike{BlogID: blog.ID, UserID: userID, CreatedAt: blog.CreatedAt}
+			}
+			// Unordered so likes that were already moved only fail on their own
+			_, err := blogLikesCollection.InsertMany(ctx, likes, options.InsertMany().SetOrdered(false))
+			if err != nil && !mongo.IsDuplicateKeyError(err) {
+				log.Printf("Error moving likes of blog %s: %v", blog.ID.Hex(), err)
+				continue
+			}
+		}
+
+		count, err := blogLikesCollection.CountDocuments(ctx, bson.M{"blog_id": blog.ID})
+		if err != nil {
+			log.Printf("Error counting likes of blog %s: %v", blog.ID.Hex(), err)
+			continue
+		}
+		_, err = blogsCollection.UpdateOne(ctx,
+			bson.M{"_id": blog.ID},
+			bson.M{"$set": bson.M{"like_count": int(count)}, "$unset": bson.M{"likes": ""}},
+		)
+		if err != nil {
+			log.Printf("Error storing like count of blog %s: %v", blog.ID.Hex(), err)
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("Moved likes of %d blogs to blog_likes", migrated)
+	}
+}
+
+// changeLikeCount adjusts like_count after a like was added or removed and
+// returns the new count. The like is already written at this point, so a
+// failed $inc falls back to recounting blog_likes instead of leaving the
+// count off by one.
+func changeLikeCount(ctx context.Context, blogID primitive.ObjectID, delta int) (int, error) {
+	var blog Blog
+	err := blogsCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": blogID},
+		bson.M{"$inc": bson.M{"like_count": delta}},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{"like_count": 1}),
+	).Decode(&blog)
+	if err == nil {
+		return blog.LikeCount, nil
+	}
+	log.Printf("Error updating like count of blog %s, recounting: %v", blogID.Hex(), err)
+	return recountLikes(blogID)
+}
+
+// recountLikes sets like_count to the number of likes in blog_likes. It has
+// its own timeout since the caller's may be what made the $inc fail.
+func recountLikes(blogID primitive.ObjectID) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := blogLikesCollection.CountDocuments(ctx, bson.M{"blog_id": blogID})
+	if err != nil {
+		return 0, err
+	}
+	_, err = blogsCollection.UpdateOne(ctx,
+		bson.M{"_id": blogID},
+		bson.M{"$set": bson.M{"like_count": int(count)}},
+	)
+	return int(count), err
+}
+
+// likedBlogIDs returns which of the blogs userID has liked
+func likedBlogIDs(ctx context.Context, userID int, blogIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	cursor, err := blogLikesCollection.Find(ctx,
+		bson.M{"user_id": userID, "blog_id": bson.M{"$in": blogIDs}},
+		options.Find().SetProjection(bson.M{"blog_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var likes []BlogLike
+	if err := cursor.All(ctx, &likes); err != nil {
+		return nil, err
+	}
+
+	liked := make(map[primitive.ObjectID]bool, len(likes))
+	for _, like := range likes {
+		liked[like.BlogID] = true
+	}
+	return liked, nil
+}
+
+// setLikedFlags fills HasLiked for an authenticated caller with one query.
+// Like setCommentPermissions, failures only leave the flag out.
+func setLikedFlags(c *gin.Context, blogs []*Blog) {
+	userID, ok := auth.UserID(c)
+	if !ok || len(blogs) == 0 {
+		return
+	}
+
+	ids := make([]primitive.ObjectID, len(blogs))
+	for i, blog := range blogs {
+		ids[i] = blog.ID
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	liked, err := likedBlogIDs(ctx, userID, ids)
+	if err != nil {
+		log.Printf("Error checking liked blogs: %v", err)
+		return
+	}
+	for _, blog := range blogs {
+		hasLiked := liked[blog.ID]
+		blog.HasLiked = &hasLiked
+	}
+}
+
+// blogRefs returns pointers into blogs for setLikedFlags
+func blogRefs(blogs []Blog) []*Blog {
+	refs := make([]*Blog, len(blogs))
+	for i := range blogs {
+		refs[i] = &blogs[i]
+	}
+	return refs
+}
+
+// GET /blogs/:id/likes?page=&limit= - users who liked a blog, most recent first
+func getBlogLikes(c *gin.Context) {
+	blogID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blog ID"})
+		return
+	}
+	page := parsePagination(c, 20, 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, ok := findVisibleBlog(ctx, c, blogID); !ok {
+		return
+	}
+
+	filter := bson.M{"blog_id": blogID}
+	total, err := blogLikesCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("Error counting likes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	cursor, err := blogLikesCollection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(page.Offset())).
+		SetLimit(int64(page.Limit)))
+	if err != nil {
+		log.Printf("Error finding likes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	likes := []BlogLike{}
+	if err := cursor.All(ctx, &likes); err != nil {
+		log.Printf("Error decoding likes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"likes":      likes,
+		"pagination": page.Response(total),
+	})
+}
+
+// decayedWeight sums weight * 0.5^(age / trendingHalfLife) over the grouped documents
+func decayedWeight(now time.Time, dateField string, weight float64) bson.M {
+	return bson.M{"$sum": bson.M{"$multiply": bson.A{
+		weight,
+		bson.M{"$pow": bson.A{0.5, bson.M{"$divide": bson.A{
+			bson.M{"$subtract": bson.A{now, dateField}},
+			trendingHalfLife.Milliseconds(),
+		}}}},
+	}}}
+}
+
+// GET /blogs/trending?limit= - published blogs ranked by recent likes and
+// comments, each counting less the older it is
+func getTrendingBlogs(c *gin.Context) {
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	since := now.Add(-trendingWindow)
+	scores := make(map[primitive.ObjectID]float64)
+
+	var grouped []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Score float64            `bson:"score"`
+	}
+	likeCursor, err := blogLikesCollection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"created_at": bson.M{"$gte": since}}},
+		bson.M{"$group": bson.M{"_id": "$blog_id", "score": decayedWeight(now, "$created_at", trendingLikeWeight)}},
+	})
+	if err == nil {
+		err = likeCursor.All(ctx, &grouped)
+	}
+	if err != nil {
+		log.Printf("Error scoring recent likes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	for _, g := range grouped {
+		scores[g.ID] += g.Score
+	}
+
+	grouped = nil
+	commentCursor, err := blogsCollection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"status": blogStatusPublished, "deleted_at": bson.M{"$exists": false}, "comments.created_at": bson.M{"$gte": since}}},
+		bson.M{"$unwind": "$comments"},
+		bson.M{"$match": bson.M{"comments.created_at": bson.M{"$gte": since}, "comments.hidden_at": bson.M{"$exists": false}}},
+		bson.M{"$group": bson.M{"_id": "$_id", "score": decayedWeight(now, "$comments.created_at", trendingCommentWeight)}},
+	})
+	if err == nil {
+		err = commentCursor.All(ctx, &grouped)
+	}
+	if err != nil {
+		log.Printf("Error scoring recent comments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	for _, g := range grouped {
+		scores[g.ID] += g.Score
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return scores[ids[i]] > scores[ids[j]] })
+	if len(ids) > trendingCandidates {
+		ids = ids[:trendingCandidates]
+	}
+
+	// Only published blogs trend, closed ones take no new activity
+	cursor, err := blogsCollection.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "status": blogStatusPublished, "deleted_at": bson.M{"$exists": false}, "hidden_at": bson.M{"$exists": false}},
+		options.Find().SetProjection(blogListProjection),
+	)
+	if err != nil {
+		log.Printf("Error finding trending blogs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	blogs := []TrendingBlog{}
+	if err := cursor.All(ctx, &blogs); err != nil {
+		log.Printf("Error decoding trending blogs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	for i := range blogs {
+		blogs[i].TrendingScore = scores[blogs[i].ID]
+	}
+	sort.Slice(blogs, func(i, j int) bool {
+		if blogs[i].TrendingScore != blogs[j].TrendingScore {
+			return blogs[i].TrendingScore > blogs[j].TrendingScore
+		}
+		return blogs[i].CreatedAt.After(blogs[j].CreatedAt)
+	})
+	if len(blogs) > limit {
+		blogs = blogs[:limit]
+	}
+
+	refs := make([]*Blog, len(blogs))
+	for i := range blogs {
+		refs[i] = &blogs[i].Blog
+	}
+	setLikedFlags(c, refs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"blogs":        blogs,
+		"window_hours": trendingWindow.Hours(),
+	})
+}
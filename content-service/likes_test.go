@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestChangeLikeCount(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	blogID := primitive.NewObjectID()
+
+	mt.Run("increments", func(mt *mtest.T) {
+		blogsCollection = mt.DB.Collection("blogs")
+		blogLikesCollection = mt.DB.Collection("blog_likes")
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: blogID},
+			{Key: "like_count", Value: 5},
+		}}))
+
+		count, err := changeLikeCount(context.Background(), blogID, 1)
+		if err != nil || count != 5 {
+			mt.Errorf("changeLikeCount = %d, %v, want 5", count, err)
+		}
+	})
+
+	mt.Run("recounts when the increment fails", func(mt *mtest.T) {
+		blogsCollection = mt.DB.Collection("blogs")
+		blogLikesCollection = mt.DB.Collection("blog_likes")
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}),
+			mtest.CreateCursorResponse(0, "soa_tours_content.blog_likes", mtest.FirstBatch, bson.D{{Key: "n", Value: 3}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		count, err := changeLikeCount(context.Background(), blogID, 1)
+		if err != nil || count != 3 {
+			mt.Errorf("changeLikeCount = %d, %v, want the recounted 3", count, err)
+		}
+		var commands []string
+		for _, event := range mt.GetAllStartedEvents() {
+			commands = append(commands, event.CommandName)
+		}
+		if len(commands) != 3 || commands[1] != "aggregate" || commands[2] != "update" {
+			mt.Errorf("commands = %v, want findAndModify, aggregate, update", commands)
+		}
+	})
+}
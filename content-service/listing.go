@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination shared by the blog listings. stakeholders-service keeps the same
+// helper, the services are separate modules and share no code.
+
+type pagination struct {
+	Page  int
+	Limit int
+}
+
+func (p pagination) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+func (p pagination) Response(total int64) gin.H {
+	return gin.H{
+		"page":        p.Page,
+		"limit":       p.Limit,
+		"total":       total,
+		"total_pages": (total + int64(p.Limit) - 1) / int64(p.Limit),
+	}
+}
+
+// parsePagination reads ?page= and ?limit=, invalid values fall back to defaults
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) pagination {
+	p := pagination{Page: 1, Limit: defaultLimit}
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			p.Page = page
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= maxLimit {
+			p.Limit = limit
+		}
+	}
+
+	return p
+}
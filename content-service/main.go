@@ -3,9 +3,6 @@ package main
 import (
     "context"
     "log"
-    "encoding/json"
-    "fmt"
-    "io"
     "net/http"
     "os"
     "strconv"
@@ -17,46 +14,68 @@ import (
     "go.mongodb.org/mongo-driver/mongo/options"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
+    "content-service/auth"
+    "content-service/clients"
     "content-service/handlers"
+    "content-service/markdown"
+    "content-service/media"
     
     
 )
 
 type Blog struct {
-    ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-    Title       string             `json:"title" bson:"title"`
-    Description string             `json:"description" bson:"description"`
-    AuthorID    int                `json:"author_id" bson:"author_id"`
-    Images      []string           `json:"images" bson:"images"`
-    Likes       []int              `json:"likes" bson:"likes"`
-    Comments    []Comment          `json:"comments" bson:"comments"`
-    CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-    UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+    ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+    Title           string             `json:"title" bson:"title"`
+    Description     string             `json:"description" bson:"description"` // Markdown source
+    DescriptionHTML string             `json:"description_html,omitempty" bson:"description_html"` // sanitized, left out of listings
+    Excerpt         string             `json:"excerpt" bson:"excerpt"` // plain text for list views
+    AuthorID        int                `json:"author_id" bson:"author_id"`
+    Images          []string           `json:"images" bson:"images"`
+    LikeCount       int                `json:"like_count" bson:"like_count"` // likes live in blog_likes
+    Comments        []Comment          `json:"comments,omitempty" bson:"comments"` // left out of responses, see getComments
+    CommentCount    int                `json:"comment_count" bson:"comment_count"`
+    Status          string             `json:"status" bson:"status"` // draft, published, closed
+    CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+    UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at"`
+    PublishedAt     *time.Time         `json:"published_at,omitempty" bson:"published_at,omitempty"`
+    ClosedAt        *time.Time         `json:"closed_at,omitempty" bson:"closed_at,omitempty"`
+    DeletedAt       *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // set while in the trash
+    HiddenAt        *time.Time         `json:"hidden_at,omitempty" bson:"hidden_at,omitempty"`   // set when a moderator hides the blog
+    CanComment      *bool              `json:"can_comment,omitempty" bson:"-"` // set for authenticated callers
+    HasLiked        *bool              `json:"has_liked,omitempty" bson:"-"`   // set for authenticated callers
 }
 
 type Comment struct {
-    UserID    int       `json:"user_id" bson:"user_id"`
-    Text      string    `json:"text" bson:"text"`
-    CreatedAt time.Time `json:"created_at" bson:"created_at"`
-    UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+    ID        primitive.ObjectID  `json:"id" bson:"_id"`
+    UserID    int                 `json:"user_id" bson:"user_id"`
+    Text      string              `json:"text" bson:"text"`
+    ParentID  *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"` // set on replies
+    Depth     int                 `json:"depth" bson:"depth"`                             // 0 for top-level comments
+    Mentions  []int               `json:"mentions,omitempty" bson:"mentions,omitempty"`   // IDs of @mentioned users
+    HiddenAt  *time.Time          `json:"-" bson:"hidden_at,omitempty"`                   // set when a moderator hides the comment
+    CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+    UpdatedAt time.Time           `json:"updated_at" bson:"updated_at"`
 }
 
 type CreateBlogRequest struct {
     Title       string   `json:"title" binding:"required,min=1,max=200"`
     Description string   `json:"description" binding:"required,min=1"`
     Images      []string `json:"images"`
+    Status      string   `json:"status" binding:"omitempty,oneof=draft published"` // draft if omitted
 }
 
 type UpdateBlogRequest struct {
-    Title       string   `json:"title" binding:"min=1,max=200"`
-    Description string   `json:"description" binding:"min=1"`
+    Title       string   `json:"title" binding:"omitempty,min=1,max=200"`
+    Description string   `json:"description" binding:"omitempty,min=1"`
     Images      []string `json:"images"`
+    Status      string   `json:"status" binding:"omitempty,oneof=draft published closed"`
 }
 
 var (
     mongoClient *mongo.Client
     blogsCollection *mongo.Collection
     db *mongo.Database  // Add this line
+    stakeholdersClient *clients.StakeholdersClient
 )
 
 func main() {
@@ -85,47 +104,82 @@ func main() {
     // Get collections
     db := mongoClient.Database("soa_tours_content")
     blogsCollection = db.Collection("blogs")
+    blogLikesCollection = db.Collection("blog_likes")
+    reportsCollection = db.Collection("reports")
+    toursCollection = db.Collection("tours")
+    backfillBlogStatus(ctx)
+    ensureBlogTrashIndex(ctx)
+    backfillCommentIDs(ctx)
+    backfillRenderedDescriptions(ctx)
+    ensureBlogLikeIndexes(ctx)
+    migrateBlogLikes(ctx)
+    ensureReportIndexes(ctx)
+    startBlogPurge()
+
+    stakeholdersClient = clients.NewStakeholdersClientFromEnv()
+
+    // Sessions are checked with stakeholders-service, so a revoke, block or
+    // role change applies here within seconds rather than at token expiry
+    tokenVerifier := auth.NewVerifier(auth.SecretFromEnv()).WithSessionCheck(auth.RemoteSessionCheckFromEnv())
+    requireAuth := auth.Middleware(tokenVerifier)
+    optionalAuth := auth.OptionalMiddleware(tokenVerifier)
+
+    // Route policies, applied after requireAuth
+    guideOnly := auth.RequireRole(auth.RoleGuide)
+    adminOnly := auth.RequireRole(auth.RoleAdmin)
+    positionOwner := auth.RequireOwnerOrAdmin("userId")
+    verifiedOnly := auth.RequireVerifiedEmail()
 
     router := gin.Default()
 
     // CORS configuration
     config := cors.DefaultConfig()
     config.AllowOrigins = []string{"http://localhost:4200", "http://127.0.0.1:4200"}
-    config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-    config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-User-ID"}
+    config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+    config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
     router.Use(cors.New(config))
 
     // Health check
     router.GET("/health", healthCheck)
 
     // Blog routes
-    router.GET("/blogs", getBlogs)
-    router.GET("/blogs/:id", getBlogByID)
-    router.POST("/blogs", createBlog)
-    router.PUT("/blogs/:id", updateBlog)
-    router.DELETE("/blogs/:id", deleteBlog)
+    router.GET("/blogs", optionalAuth, getBlogs)
+    router.GET("/blogs/feed", requireAuth, getBlogFeed)
+    router.GET("/blogs/search", optionalAuth, searchBlogs)
+    router.GET("/blogs/trending", optionalAuth, getTrendingBlogs)
+    router.GET("/blogs/:id", optionalAuth, getBlogByID)
+    router.POST("/blogs", requireAuth, createBlog)
+    router.POST("/blogs/preview", requireAuth, previewBlog)
+    router.PUT("/blogs/:id", requireAuth, updateBlog)
+    router.DELETE("/blogs/:id", requireAuth, deleteBlog)
+    router.GET("/blogs/deleted", requireAuth, getDeletedBlogs)
+    router.POST("/blogs/:id/restore", requireAuth, restoreBlog)
     
     // Blog interaction routes
-    router.POST("/blogs/:id/like", likeBlog)
-    router.DELETE("/blogs/:id/like", unlikeBlog)
-    router.POST("/blogs/:id/comments", addComment)
+    router.POST("/blogs/:id/like", requireAuth, likeBlog)
+    router.DELETE("/blogs/:id/like", requireAuth, unlikeBlog)
+    router.GET("/blogs/:id/likes", optionalAuth, getBlogLikes)
+    router.GET("/blogs/:id/comments", optionalAuth, getComments)
+    router.POST("/blogs/:id/comments", requireAuth, verifiedOnly, addComment)
+    router.PUT("/blogs/:id/comments/:commentId", requireAuth, verifiedOnly, updateComment)
+    router.DELETE("/blogs/:id/comments/:commentId", requireAuth, deleteComment)
 
     tourHandler := handlers.NewTourHandler(db)
 
     // Tour rute
-    router.GET("/tours", tourHandler.GetTours)
-    router.GET("/tours/:id", tourHandler.GetTourByID) 
-    router.POST("/tours", AuthMiddleware(), tourHandler.CreateTour)
-    router.PUT("/tours/:id", AuthMiddleware(), tourHandler.UpdateTour)
+    router.GET("/tours", optionalAuth, tourHandler.GetTours)
+    router.GET("/tours/:id", optionalAuth, tourHandler.GetTourByID)
+    router.POST("/tours", requireAuth, guideOnly, tourHandler.CreateTour)
+    router.PUT("/tours/:id", requireAuth, guideOnly, tourHandler.UpdateTour)
 
     // Keypoint rute
-    router.POST("/tours/:id/keypoints", AuthMiddleware(), tourHandler.AddKeypoint)
-    router.PUT("/tours/:id/keypoints/:order", AuthMiddleware(), tourHandler.UpdateKeypoint)
-    router.DELETE("/tours/:id/keypoints/:order", AuthMiddleware(), tourHandler.RemoveKeypoint)
+    router.POST("/tours/:id/keypoints", requireAuth, guideOnly, tourHandler.AddKeypoint)
+    router.PUT("/tours/:id/keypoints/:order", requireAuth, guideOnly, tourHandler.UpdateKeypoint)
+    router.DELETE("/tours/:id/keypoints/:order", requireAuth, guideOnly, tourHandler.RemoveKeypoint)
 
-    router.DELETE("/tours/:id/keypoints", AuthMiddleware(), tourHandler.ClearAllKeypoints)
-    router.POST("/tours/:id/transport-times", AuthMiddleware(), tourHandler.AddTransportTime)
-    router.DELETE("/tours/:id/transport-times/:type", AuthMiddleware(), tourHandler.RemoveTransportTime)
+    router.DELETE("/tours/:id/keypoints", requireAuth, guideOnly, tourHandler.ClearAllKeypoints)
+    router.POST("/tours/:id/transport-times", requireAuth, guideOnly, tourHandler.AddTransportTime)
+    router.DELETE("/tours/:id/transport-times/:type", requireAuth, guideOnly, tourHandler.RemoveTransportTime)
 
     // Tours routes (placeholder for future implementation)
     //router.GET("/tours", getToursPlaceholder)
@@ -133,18 +187,42 @@ func main() {
     positionHandler := handlers.NewPositionHandler(db)
 
     // Position routes
-    router.GET("/positions", positionHandler.GetAllPositions)
-    router.GET("/positions/:userId", positionHandler.GetUserPosition)
-    router.POST("/positions/:userId", positionHandler.UpdateUserPosition)
-    router.DELETE("/positions/:userId", positionHandler.ClearUserPosition)
+    router.GET("/positions", requireAuth, adminOnly, positionHandler.GetAllPositions)
+    router.GET("/positions/:userId", requireAuth, positionOwner, positionHandler.GetUserPosition)
+    router.POST("/positions/:userId", requireAuth, positionOwner, positionHandler.UpdateUserPosition)
+    router.DELETE("/positions/:userId", requireAuth, positionOwner, positionHandler.ClearUserPosition)
 
     executionHandler := handlers.NewTourExecutionHandler(db)
 
     // Tour Execution routes
-    router.POST("/tours/start", executionHandler.StartTour)
-    router.POST("/tours/check-keypoints", executionHandler.CheckKeypoints)
-    router.PUT("/executions/:executionId/abandon", executionHandler.AbandonTour)  // ✅ PROMENJENA RUTA
-    router.GET("/tours/executions", executionHandler.GetUserExecutions)
+    router.POST("/tours/start", requireAuth, executionHandler.StartTour)
+    router.POST("/tours/check-keypoints", requireAuth, executionHandler.CheckKeypoints)
+    router.PUT("/executions/:executionId/abandon", requireAuth, executionHandler.AbandonTour)  // ✅ PROMENJENA RUTA
+    router.GET("/tours/executions", requireAuth, executionHandler.GetUserExecutions)
+
+    // Moderation routes, hidden blogs, comments and reviews are left out of all listings
+    router.POST("/reports", requireAuth, createReport)
+    moderation := router.Group("/admin", requireAuth, adminOnly)
+    moderation.GET("/reports", getReports)
+    moderation.POST("/reports/:id/dismiss", dismissReport)
+    moderation.POST("/reports/:id/action", actionReport)
+
+    mediaStorage := media.FromEnv()
+    mediaHandler := handlers.NewMediaHandler(db, mediaStorage)
+
+    // Media routes, the returned URLs go into profile, blog, keypoint and review images
+    router.POST("/media", requireAuth, mediaHandler.UploadMedia)
+    router.GET("/media/files/*key", mediaHandler.ServeFile)
+    router.GET("/media/:id", mediaHandler.GetMedia)
+    router.DELETE("/media/:id", requireAuth, mediaHandler.DeleteMedia)
+
+    userDataHandler := handlers.NewUserDataHandler(db, mediaStorage)
+
+    // Personal data export and erasure, called by stakeholders-service with the
+    // caller's token. /internal is not exposed through the api-gateway.
+    internal := router.Group("/internal", requireAuth, auth.RequireOwnerOrAdmin("id"))
+    internal.GET("/users/:id/export", userDataHandler.ExportUserData)
+    internal.DELETE("/users/:id/data", userDataHandler.DeleteUserData)
    
 
     port := os.Getenv("PORT")
@@ -156,18 +234,6 @@ func main() {
     log.Fatal(router.Run("0.0.0.0:" + port))
 }
 
-func AuthMiddleware() gin.HandlerFunc {
-    return func(c *gin.Context) {
-        userID := c.GetHeader("X-User-ID")
-        if userID == "" {
-            c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
-            c.Abort()
-            return
-        }
-        c.Next()
-    }
-}
-
 func healthCheck(c *gin.Context) {
     c.JSON(http.StatusOK, gin.H{
         "status":    "healthy",
@@ -181,31 +247,38 @@ func getBlogs(c *gin.Context) {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    // Get pagination parameters
-    page := 1
-    limit := 10
-    
-    if pageStr := c.Query("page"); pageStr != "" {
-        if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-            page = p
+    page := parsePagination(c, 10, 50)
+
+    // Visibility is kept under $and so the status filter below cannot replace it
+    filter := bson.M{"$and": bson.A{visibleBlogsFilter(c)}}
+
+    // Filter by author if specified
+    if authorIDStr := c.Query("author_id"); authorIDStr != "" {
+        authorID, err := strconv.Atoi(authorIDStr)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+            return
         }
+        filter["author_id"] = authorID
     }
-    
-    if limitStr := c.Query("limit"); limitStr != "" {
-        if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
-            limit = l
+
+    // Filter by status if specified, drafts stay limited to the caller's own
+    if status := c.Query("status"); status != "" {
+        if _, ok := blogTransitions[status]; !ok {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use draft, published or closed"})
+            return
         }
+        filter["status"] = status
     }
 
-    skip := (page - 1) * limit
-
     // Find blogs with pagination, sorted by creation date (newest first)
     findOptions := options.Find()
     findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
-    findOptions.SetLimit(int64(limit))
-    findOptions.SetSkip(int64(skip))
+    findOptions.SetLimit(int64(page.Limit))
+    findOptions.SetSkip(int64(page.Offset()))
+    findOptions.SetProjection(blogListProjection)
 
-    cursor, err := blogsCollection.Find(ctx, bson.M{}, findOptions)
+    cursor, err := blogsCollection.Find(ctx, filter, findOptions)
     if err != nil {
         log.Printf("Error finding blogs: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -221,20 +294,18 @@ func getBlogs(c *gin.Context) {
     }
 
     // Get total count
-    total, err := blogsCollection.CountDocuments(ctx, bson.M{})
+    total, err := blogsCollection.CountDocuments(ctx, filter)
     if err != nil {
         log.Printf("Error counting blogs: %v", err)
         total = 0
     }
 
+    setCommentPermissions(c, blogs)
+    setLikedFlags(c, blogRefs(blogs))
+
     c.JSON(http.StatusOK, gin.H{
-        "blogs": blogs,
-        "pagination": gin.H{
-            "page":        page,
-            "limit":       limit,
-            "total":       total,
-            "total_pages": (total + int64(limit) - 1) / int64(limit),
-        },
+        "blogs":      blogs,
+        "pagination": page.Response(total),
     })
 }
 
@@ -249,19 +320,17 @@ func getBlogByID(c *gin.Context) {
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
-    var blog Blog
-    err = blogsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&blog)
-    if err != nil {
-        if err == mongo.ErrNoDocuments {
-            c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
-            return
-        }
-        log.Printf("Error finding blog: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+    blog, ok := findVisibleBlog(ctx, c, objectID)
+    if !ok {
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"blog": blog})
+    blog.Comments = nil // paged by GET /blogs/:id/comments
+    blogs := []Blog{*blog}
+    setCommentPermissions(c, blogs)
+    setLikedFlags(c, blogRefs(blogs))
+
+    c.JSON(http.StatusOK, gin.H{"blog": blogs[0]})
 }
 
 func createBlog(c *gin.Context) {
@@ -271,25 +340,33 @@ func createBlog(c *gin.Context) {
         return
     }
 
-    // Get author ID from request header or context
-    // For now, we'll use a dummy author ID or get it from header
-    authorID := 1 // Default to admin
-    if authorIDStr := c.GetHeader("X-User-ID"); authorIDStr != "" {
-        if id, err := strconv.Atoi(authorIDStr); err == nil {
-            authorID = id
-        }
+    // Author is the authenticated caller
+    authorID := c.GetInt(auth.ContextUserID)
+
+    rendered, err := markdown.Render(req.Description)
+    if err != nil {
+        log.Printf("Error rendering blog description: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render description"})
+        return
     }
 
     // Create new blog
+    now := time.Now()
     blog := Blog{
-        Title:       req.Title,
-        Description: req.Description,
-        AuthorID:    authorID,
-        Images:      req.Images,
-        Likes:       []int{},
-        Comments:    []Comment{},
-        CreatedAt:   time.Now(),
-        UpdatedAt:   time.Now(),
+        Title:           req.Title,
+        Description:     req.Description,
+        DescriptionHTML: rendered.HTML,
+        Excerpt:         rendered.Excerpt,
+        AuthorID:        authorID,
+        Images:          req.Images,
+        Comments:        []Comment{},
+        Status:          blogStatusDraft,
+        CreatedAt:       now,
+        UpdatedAt:       now,
+    }
+    if req.Status == blogStatusPublished {
+        blog.Status = blogStatusPublished
+        blog.PublishedAt = &now
     }
 
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -324,9 +401,23 @@ func updateBlog(c *gin.Context) {
         return
     }
 
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+
+    blog, ok := findVisibleBlog(ctx, c, objectID)
+    if !ok {
+        return
+    }
+
+    if !auth.IsOwnerOrAdmin(c, blog.AuthorID) {
+        auth.Forbidden(c, "You can only update your own blogs")
+        return
+    }
+
     // Build update document
+    now := time.Now()
     updateDoc := bson.M{
-        "updated_at": time.Now(),
+        "updated_at": now,
     }
 
     if req.Title != "" {
@@ -334,17 +425,34 @@ func updateBlog(c *gin.Context) {
     }
     if req.Description != "" {
         updateDoc["description"] = req.Description
+        rendered, err := renderedDescription(req.Description)
+        if err != nil {
+            log.Printf("Error rendering blog description: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render description"})
+            return
+        }
+        for field, value := range rendered {
+            updateDoc[field] = value
+        }
     }
     if req.Images != nil {
         updateDoc["images"] = req.Images
     }
+    if req.Status != "" && req.Status != blog.Status {
+        statusUpdate, err := blogStatusUpdate(blog, req.Status, now)
+        if err != nil {
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            return
+        }
+        for field, value := range statusUpdate {
+            updateDoc[field] = value
+        }
+    }
 
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
-
+    // The status in the filter makes a concurrent status change fail instead of being overwritten
     result, err := blogsCollection.UpdateOne(
         ctx,
-        bson.M{"_id": objectID},
+        bson.M{"_id": objectID, "status": blog.Status},
         bson.M{"$set": updateDoc},
     )
     if err != nil {
@@ -354,13 +462,15 @@ func updateBlog(c *gin.Context) {
     }
 
     if result.MatchedCount == 0 {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+        c.JSON(http.StatusConflict, gin.H{"error": "Blog was changed concurrently, try again"})
         return
     }
 
     c.JSON(http.StatusOK, gin.H{"message": "Blog updated successfully"})
 }
 
+// DELETE /blogs/:id - move a blog to the trash, it can be restored until
+// blogRestoreWindow has passed and is purged after that
 func deleteBlog(c *gin.Context) {
     idStr := c.Param("id")
     objectID, err := primitive.ObjectIDFromHex(idStr)
@@ -372,19 +482,37 @@ func deleteBlog(c *gin.Context) {
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
-    result, err := blogsCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+    blog, ok := findVisibleBlog(ctx, c, objectID)
+    if !ok {
+        return
+    }
+
+    if !auth.IsOwnerOrAdmin(c, blog.AuthorID) {
+        auth.Forbidden(c, "You can only delete your own blogs")
+        return
+    }
+
+    now := time.Now()
+    result, err := blogsCollection.UpdateOne(
+        ctx,
+        bson.M{"_id": objectID, "deleted_at": bson.M{"$exists": false}},
+        bson.M{"$set": bson.M{"deleted_at": now}},
+    )
     if err != nil {
         log.Printf("Error deleting blog: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete blog"})
         return
     }
 
-    if result.DeletedCount == 0 {
+    if result.MatchedCount == 0 {
         c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Blog deleted successfully"})
+    c.JSON(http.StatusOK, gin.H{
+        "message":       "Blog deleted successfully",
+        "restore_until": now.Add(blogRestoreWindow),
+    })
 }
 
 func likeBlog(c *gin.Context) {
@@ -395,30 +523,36 @@ func likeBlog(c *gin.Context) {
         return
     }
 
-    // Get user ID from header
-    userID := 1 // Default
-    if userIDStr := c.GetHeader("X-User-ID"); userIDStr != "" {
-        if id, err := strconv.Atoi(userIDStr); err == nil {
-            userID = id
-        }
-    }
+    userID := c.GetInt(auth.ContextUserID)
 
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
-    // Add user to likes array if not already present
-    _, err = blogsCollection.UpdateOne(
-        ctx,
-        bson.M{"_id": objectID},
-        bson.M{"$addToSet": bson.M{"likes": userID}},
-    )
+    blog, ok := findVisibleBlog(ctx, c, objectID)
+    if !ok || !requireOpenBlog(c, blog) {
+        return
+    }
+
+    // The unique index turns a second like into a duplicate key error
+    _, err = blogLikesCollection.InsertOne(ctx, BlogLike{BlogID: objectID, UserID: userID, CreatedAt: time.Now()})
+    if mongo.IsDuplicateKeyError(err) {
+        c.JSON(http.StatusOK, gin.H{"message": "Blog already liked", "like_count": blog.LikeCount, "has_liked": true})
+        return
+    }
     if err != nil {
         log.Printf("Error liking blog: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to like blog"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Blog liked successfully"})
+    likeCount, err := changeLikeCount(ctx, objectID, 1)
+    if err != nil {
+        log.Printf("Error updating like count: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to like blog"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Blog liked successfully", "like_count": likeCount, "has_liked": true})
 }
 
 func unlikeBlog(c *gin.Context) {
@@ -429,30 +563,34 @@ func unlikeBlog(c *gin.Context) {
         return
     }
 
-    // Get user ID from header
-    userID := 1 // Default
-    if userIDStr := c.GetHeader("X-User-ID"); userIDStr != "" {
-        if id, err := strconv.Atoi(userIDStr); err == nil {
-            userID = id
-        }
-    }
+    userID := c.GetInt(auth.ContextUserID)
 
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
-    // Remove user from likes array
-    _, err = blogsCollection.UpdateOne(
-        ctx,
-        bson.M{"_id": objectID},
-        bson.M{"$pull": bson.M{"likes": userID}},
-    )
+    blog, ok := findVisibleBlog(ctx, c, objectID)
+    if !ok || !requireOpenBlog(c, blog) {
+        return
+    }
+
+    result, err := blogLikesCollection.DeleteOne(ctx, bson.M{"blog_id": objectID, "user_id": userID})
     if err != nil {
         log.Printf("Error unliking blog: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlike blog"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Blog unliked successfully"})
+    // Only a removed like changes the count
+    likeCount := blog.LikeCount
+    if result.DeletedCount > 0 {
+        if likeCount, err = changeLikeCount(ctx, objectID, -1); err != nil {
+            log.Printf("Error updating like count: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlike blog"})
+            return
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Blog unliked successfully", "like_count": likeCount, "has_liked": false})
 }
 
 func addComment(c *gin.Context) {
@@ -465,52 +603,38 @@ func addComment(c *gin.Context) {
 
     var req struct {
         Text string `json:"text" binding:"required,min=1"`
-        AuthorID int `json:"author_id"` // Dodano - može biti opciono
+        ParentID string `json:"parent_id"` // comment being replied to
     }
     if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    // Get user ID from header
-    userID := 1 // Default
-    if userIDStr := c.GetHeader("X-User-ID"); userIDStr != "" {
-        if id, err := strconv.Atoi(userIDStr); err == nil {
-            userID = id
-        }
-    }
+    userID := c.GetInt(auth.ContextUserID)
 
     // NOVA LOGIKA: Prvo dobij blog da vidiš ko je autor
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
-    var blog Blog
-    err = blogsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&blog)
-    if err != nil {
-        if err == mongo.ErrNoDocuments {
-            c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
-            return
-        }
-        log.Printf("Error finding blog: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+    blog, ok := findVisibleBlog(ctx, c, objectID)
+    if !ok || !requireOpenBlog(c, blog) {
         return
     }
 
-    // Koristi author_id iz zahteva ili iz bloga
-    authorID := blog.AuthorID
-    if req.AuthorID != 0 {
-        authorID = req.AuthorID
+    parentID, depth, ok := replyParent(c, blog, req.ParentID)
+    if !ok {
+        return
     }
 
+    // The follow check is always against the blog's own author
+    authorID := blog.AuthorID
+
     // KLJUČNA PROVERA: Pozovi stakeholders-service da proveri follow status
-    canComment, err := canUserComment(userID, authorID)
+    canComment, err := stakeholdersClient.CanComment(ctx, c.GetHeader("Authorization"), userID, authorID)
     if err != nil {
         log.Printf("Error checking follow status: %v", err)
         // Ako nije moguće proveriti, odbaci zahtev iz bezbednosnih razloga
-        c.JSON(http.StatusInternalServerError, gin.H{
-            "error": "Failed to verify follow status",
-            "details": err.Error(),
-        })
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify follow status"})
         return
     }
 
@@ -525,23 +649,37 @@ func addComment(c *gin.Context) {
 
     // Follow provera prošla - dodaj komentar
     comment := Comment{
+        ID:        primitive.NewObjectID(),
         UserID:    userID,
         Text:      req.Text,
+        ParentID:  parentID,
+        Depth:     depth,
+        Mentions:  resolveMentions(ctx, c, req.Text),
         CreatedAt: time.Now(),
         UpdatedAt: time.Now(),
     }
 
-    // Add comment to comments array
-    _, err = blogsCollection.UpdateOne(
+    // Add comment to comments array, the filter repeats the checks above in
+    // case the blog was closed, deleted or hidden since it was read
+    result, err := blogsCollection.UpdateOne(
         ctx,
-        bson.M{"_id": objectID},
-        bson.M{"$push": bson.M{"comments": comment}},
+        bson.M{
+            "_id":        objectID,
+            "status":     blogStatusPublished,
+            "deleted_at": bson.M{"$exists": false},
+            "hidden_at":  bson.M{"$exists": false},
+        },
+        bson.M{"$push": bson.M{"comments": comment}, "$inc": bson.M{"comment_count": 1}},
     )
     if err != nil {
         log.Printf("Error adding comment: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
         return
     }
+    if result.MatchedCount == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Blog is no longer open for comments"})
+        return
+    }
 
     c.JSON(http.StatusCreated, gin.H{
         "message": "Comment added successfully - follow check passed",
@@ -557,46 +695,30 @@ func getToursPlaceholder(c *gin.Context) {
     })
 }
 
-// Proveri da li korisnik može da komentariše blog
-func canUserComment(userID int, authorID int) (bool, error) {
-    stakeholdersURL := os.Getenv("STAKEHOLDERS_SERVICE_URL")
-    if stakeholdersURL == "" {
-        stakeholdersURL = "stakeholders-service:8081"
+// setCommentPermissions fills CanComment for an authenticated caller with a
+// single batch call. Failures only leave the flag out, the listing still works.
+func setCommentPermissions(c *gin.Context, blogs []Blog) {
+    userID, ok := auth.UserID(c)
+    if !ok || len(blogs) == 0 {
+        return
     }
 
-    url := fmt.Sprintf("http://%s/can-comment/%d", stakeholdersURL, authorID)
-    
-    // Kreiranje HTTP zahteva sa mock user_id header-om
-    req, err := http.NewRequest("GET", url, nil)
-    if err != nil {
-        return false, err
+    pairs := make([]clients.CommentPair, len(blogs))
+    for i, blog := range blogs {
+        pairs[i] = clients.CommentPair{UserID: userID, AuthorID: blog.AuthorID}
     }
-    
-    // Dodaj header da simulira authenticated user
-    req.Header.Set("X-User-ID", fmt.Sprintf("%d", userID))
 
-    client := &http.Client{}
-    resp, err := client.Do(req)
-    if err != nil {
-        return false, err
-    }
-    defer resp.Body.Close()
+    ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+    defer cancel()
 
-    body, err := io.ReadAll(resp.Body)
+    permissions, err := stakeholdersClient.CanCommentBatch(ctx, c.GetHeader("Authorization"), pairs)
     if err != nil {
-        return false, err
+        log.Printf("Error checking comment permissions: %v", err)
+        return
     }
 
-    var result map[string]interface{}
-    if err := json.Unmarshal(body, &result); err != nil {
-        return false, err
+    for i := range blogs {
+        canComment := permissions[pairs[i]]
+        blogs[i].CanComment = &canComment
     }
-
-    canComment, ok := result["can_comment"].(bool)
-    if !ok {
-        return false, fmt.Errorf("unexpected response format")
-    }
-
-    return canComment, nil
 }
-
@@ -0,0 +1,97 @@
+// Package markdown renders blog bodies written in Markdown to sanitized HTML
+// and plain text. Rendering happens when a blog is saved, clients get the
+// stored result and never need a renderer of their own.
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// ExcerptLength is the maximum excerpt length in characters
+const ExcerptLength = 280
+
+var (
+	// GitHub flavoured Markdown. Raw HTML in the source is dropped by goldmark
+	// and the output is sanitized anyway, so neither can be used for XSS.
+	renderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// User generated content policy: formatting, links and http(s) images,
+	// no scripts, styles, event handlers or javascript: URLs
+	sanitizer = func() *bluemonday.Policy {
+		policy := bluemonday.UGCPolicy()
+		policy.AllowURLSchemes("http", "https", "mailto")
+		policy.RequireNoFollowOnLinks(true)
+		policy.AddTargetBlankToFullyQualifiedLinks(true)
+		return policy
+	}()
+
+	stripTags = bluemonday.StrictPolicy()
+)
+
+// Rendered is a Markdown source rendered for display
+type Rendered struct {
+	HTML    string
+	Excerpt string
+}
+
+// Render converts source to sanitized HTML and a plain-text excerpt
+func Render(source string) (Rendered, error) {
+	htmlOutput, err := ToHTML(source)
+	if err != nil {
+		return Rendered{}, err
+	}
+	return Rendered{
+		HTML:    htmlOutput,
+		Excerpt: Excerpt(htmlToText(htmlOutput), ExcerptLength),
+	}, nil
+}
+
+// ToHTML renders source and sanitizes the result
+func ToHTML(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return sanitizer.Sanitize(buf.String()), nil
+}
+
+// PlainText renders source and keeps only its text, e.g. for search snippets
+func PlainText(source string) string {
+	htmlOutput, err := ToHTML(source)
+	if err != nil {
+		return source
+	}
+	return htmlToText(htmlOutput)
+}
+
+// htmlToText drops the tags of rendered HTML. Block ends become spaces so
+// words of adjacent paragraphs or list items do not run together.
+func htmlToText(htmlOutput string) string {
+	htmlOutput = strings.NewReplacer("</", " </", "<br", " <br").Replace(htmlOutput)
+	text := html.UnescapeString(stripTags.Sanitize(htmlOutput))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Excerpt shortens text to at most limit characters, cutting at a word boundary
+func Excerpt(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	end := 0
+	for count := 0; count < limit; count++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	if space := strings.LastIndexByte(text[:end], ' '); space > 0 {
+		end = space
+	}
+	return strings.TrimRight(text[:end], " ,.;:") + "…"
+}
@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTMLStripsScripts(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		forbidden []string
+	}{
+		{"script tag", "hello <script>alert(1)</script>", []string{"<script", "alert(1)</script>"}},
+		{"event handler", `<img src="x" onerror="alert(1)">`, []string{"onerror"}},
+		{"javascript link", "[click](javascript:alert(1))", []string{"javascript:"}},
+		{"javascript image", "![x](javascript:alert(1))", []string{"javascript:"}},
+		{"data link", "[click](data:text/html;base64,PHNjcmlwdD4=)", []string{"data:"}},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`, []string{"<iframe"}},
+		{"style", `<style>body{display:none}</style>`, []string{"<style"}},
+		{"autolink", "<javascript:alert(1)>", []string{`href="javascript:`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToHTML(tt.source)
+			if err != nil {
+				t.Fatalf("ToHTML: %v", err)
+			}
+			for _, bad := range tt.forbidden {
+				if strings.Contains(strings.ToLower(got), bad) {
+					t.Errorf("ToHTML(%q) = %q, contains %q", tt.source, got, bad)
+				}
+			}
+		})
+	}
+}
+
+func TestToHTMLKeepsFormatting(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"**bold**", "<strong>bold</strong>"},
+		{"~~gone~~", "<del>gone</del>"},
+		{"[site](https://example.com)", `href="https://example.com"`},
+		{"[site](https://example.com)", `rel="nofollow noopener"`},
+		{"![alt](https://example.com/a.png)", `<img src="https://example.com/a.png" alt="alt"`},
+		{"a < b", "a &lt; b"},
+	}
+	for _, tt := range tests {
+		got, err := ToHTML(tt.source)
+		if err != nil {
+			t.Fatalf("ToHTML: %v", err)
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("ToHTML(%q) = %q, want it to contain %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"one two three", 9, "one two…"},
+		{"one, two three", 6, "one…"},
+		{"čćžšđ čćž", 7, "čćžšđ…"},
+	}
+	for _, tt := range tests {
+		if got := Excerpt(tt.text, tt.limit); got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	if got, want := PlainText("# Title\n\nFirst para.\n\n- one\n- two"), "Title First para. one two"; got != want {
+		t.Errorf("PlainText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+const (
+	MaxUploadBytes = 5 << 20
+	// Larger images are rejected before decoding to keep memory bounded. A
+	// small compressed file can describe a huge canvas, the pixel cap limits
+	// the decoded image to about 100 MB of RGBA.
+	maxDimension  = 8000
+	maxPixels     = 25_000_000
+	thumbnailSize = 320
+)
+
+var (
+	ErrTooLarge        = fmt.Errorf("image is larger than %d MB", MaxUploadBytes>>20)
+	ErrUnsupportedType = errors.New("only JPEG, PNG, GIF and WebP images are allowed")
+	ErrInvalidImage    = errors.New("file is not a valid image")
+)
+
+// Allowed upload types and the extension stored files get
+var allowedTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// Image is a validated upload with its thumbnail, ready to be stored
+type Image struct {
+	Key                  string
+	ContentType          string
+	Data                 []byte
+	Width                int
+	Height               int
+	ThumbnailKey         string
+	ThumbnailContentType string
+	Thumbnail            []byte
+}
+
+// ProcessImage checks size and the sniffed content type (the client supplied
+// one is not trusted), decodes the image and renders a thumbnail
+func ProcessImage(data []byte) (*Image, error) {
+	if len(data) > MaxUploadBytes {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return nil, fmt.Errorf("image dimensions exceed %dx%d", maxDimension, maxDimension)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image exceeds %d megapixels", maxPixels/1_000_000)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	thumbnail, thumbnailType, thumbnailExt, err := renderThumbnail(src, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := newKeyBase()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Key:                  base + "." + ext,
+		ContentType:          contentType,
+		Data:                 data,
+		Width:                config.Width,
+		Height:               config.Height,
+		ThumbnailKey:         base + "_thumb." + thumbnailExt,
+		ThumbnailContentType: thumbnailType,
+		Thumbnail:            thumbnail,
+	}, nil
+}
+
+// renderThumbnail scales src to fit thumbnailSize, PNG keeps transparency for
+// PNG and GIF sources, everything else becomes JPEG
+func renderThumbnail(src image.Image, contentType string) ([]byte, string, string, error) {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailSize || height > thumbnailSize {
+		if width >= height {
+			width, height = thumbnailSize, max(1, height*thumbnailSize/width)
+		} else {
+			width, height = max(1, width*thumbnailSize/height), thumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if contentType == "image/png" || contentType == "image/gif" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", "png", nil
+	}
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/jpeg", "jpg", nil
+}
+
+// newKeyBase returns "yyyy/mm/<random>", random names keep URLs unguessable
+func newKeyBase() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("2006/01") + "/" + hex.EncodeToString(raw), nil
+}
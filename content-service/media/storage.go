@@ -0,0 +1,53 @@
+// Package media stores uploaded images and their thumbnails behind a Storage
+// interface. The local filesystem is the default backend, any S3-compatible
+// object store (AWS S3, MinIO, ...) can be used instead.
+package media
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+)
+
+// Storage keeps files under keys like "2025/01/ab12...ef.jpg" and returns
+// the public URL of a key. URLs never change once returned, the image fields
+// of profiles, blogs, keypoints and reviews store them.
+type Storage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// FromEnv picks the backend from MEDIA_STORAGE: "s3" uses S3_ENDPOINT,
+// S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY and S3_PUBLIC_URL,
+// anything else stores files in MEDIA_DIR served under MEDIA_PUBLIC_URL
+func FromEnv() Storage {
+	if os.Getenv("MEDIA_STORAGE") == "s3" {
+		storage := NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    envOr("S3_REGION", "us-east-1"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		})
+		log.Printf("Storing media in S3 bucket %s", os.Getenv("S3_BUCKET"))
+		return storage
+	}
+
+	dir := envOr("MEDIA_DIR", "./uploads")
+	log.Printf("Storing media in %s", dir)
+	return NewLocalStorage(dir, envOr("MEDIA_PUBLIC_URL", "http://localhost:8080/content")+"/media/files")
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
@@ -34,12 +34,13 @@ type Tour struct {
 }
 
 type Review struct {
-    UserID     int       `bson:"user_id" json:"user_id"`
-    Rating     int       `bson:"rating" json:"rating"` // 1-5
-    Comment    string    `bson:"comment" json:"comment"`
-    VisitDate  time.Time `bson:"visit_date" json:"visit_date"`
-    CreatedAt  time.Time `bson:"created_at" json:"created_at"`
-    Images     []string  `bson:"images" json:"images"`
+    UserID     int        `bson:"user_id" json:"user_id"`
+    Rating     int        `bson:"rating" json:"rating"` // 1-5
+    Comment    string     `bson:"comment" json:"comment"`
+    VisitDate  time.Time  `bson:"visit_date" json:"visit_date"`
+    CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+    Images     []string   `bson:"images" json:"images"`
+    HiddenAt   *time.Time `bson:"hidden_at,omitempty" json:"-"` // set when a moderator hides the review
 }
 
 type CreateTourRequest struct {
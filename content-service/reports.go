@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"content-service/auth"
+	"content-service/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Users report blogs, comments and tour reviews into a moderation queue.
+// Admins dismiss a report or act on it by hiding the content, blocking its
+// author through stakeholders-service, or both. Either way every open report
+// on the same content is resolved together. Hidden content stays stored
+// with hidden_at set and is left out of all listings.
+
+const (
+	reportTargetBlog    = "blog"
+	reportTargetComment = "comment"
+	reportTargetReview  = "review"
+
+	reportStatusOpen      = "open"
+	reportStatusDismissed = "dismissed"
+	reportStatusActioned  = "actioned"
+
+	// Stored with the report so moderators see what was reported even
+	// after the author edits it
+	reportSnapshotLength = 500
+)
+
+var (
+	reportsCollection *mongo.Collection
+	toursCollection   *mongo.Collection
+)
+
+type Report struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	TargetType   string              `json:"target_type" bson:"target_type"` // blog, comment, review
+	TargetKey    string              `json:"-" bson:"target_key"`            // same for all reports on one item
+	BlogID       *primitive.ObjectID `json:"blog_id,omitempty" bson:"blog_id,omitempty"`
+	CommentID    *primitive.ObjectID `json:"comment_id,omitempty" bson:"comment_id,omitempty"`
+	TourID       *primitive.ObjectID `json:"tour_id,omitempty" bson:"tour_id,omitempty"`
+	ReviewUserID *int                `json:"review_user_id,omitempty" bson:"review_user_id,omitempty"` // reviews are keyed by tour and reviewer
+	AuthorID     int                 `json:"author_id" bson:"author_id"`                               // author of the reported content
+	Content      string              `json:"content" bson:"content"`                                   // snapshot at report time
+	ReporterID   int                 `json:"reporter_id" bson:"reporter_id"`
+	Reason       string              `json:"reason" bson:"reason"`
+	Details      string              `json:"details,omitempty" bson:"details,omitempty"`
+	Status       string              `json:"status" bson:"status"` // open, dismissed, actioned
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at"`
+	ResolvedAt   *time.Time          `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	ResolvedBy   *int                `json:"resolved_by,omitempty" bson:"resolved_by,omitempty"`
+	Resolution   *ReportResolution   `json:"resolution,omitempty" bson:"resolution,omitempty"`
+}
+
+type ReportResolution struct {
+	ContentHidden bool   `json:"content_hidden" bson:"content_hidden"`
+	AuthorBlocked bool   `json:"author_blocked" bson:"author_blocked"`
+	Note          string `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+type CreateReportRequest struct {
+	TargetType   string `json:"target_type" binding:"required,oneof=blog comment review"`
+	BlogID       string `json:"blog_id"`        // blog and comment reports
+	CommentID    string `json:"comment_id"`     // comment reports
+	TourID       string `json:"tour_id"`        // review reports
+	ReviewUserID int    `json:"review_user_id"` // review reports
+	Reason       string `json:"reason" binding:"required,oneof=spam harassment hate_speech inappropriate misinformation other"`
+	Details      string `json:"details" binding:"max=1000"`
+}
+
+type DismissReportRequest struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+type ActionReportRequest struct {
+	Hide        bool   `json:"hide"`
+	BlockAuthor bool   `json:"block_author"`
+	Note        string `json:"note" binding:"max=255"` // also the block reason when given
+}
+
+func ensureReportIndexes(ctx context.Context) {
+	_, err := reportsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// A user has at most one open report per item
+		{
+			Keys: bson.D{{Key: "target_key", Value: 1}, {Key: "reporter_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": reportStatusOpen}),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "target_key", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "reporter_id", Value: 1}}},
+		{Keys: bson.D{{Key: "author_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating report indexes: %v", err)
+	}
+}
+
+func snapshot(text string) string {
+	runes := []rune(text)
+	if len(runes) <= reportSnapshotLength {
+		return text
+	}
+	return string(runes[:reportSnapshotLength]) + "…"
+}
+
+// objectIDField parses a required ID of the request body, writing 400 on failure
+func objectIDField(c *gin.Context, value, name string) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing " + name})
+		return id, false
+	}
+	return id, true
+}
+
+// reportTarget finds the reported content and returns a report describing
+// it, writing the error response otherwise. Content the caller cannot see,
+// including hidden content, is reported as missing.
+func reportTarget(ctx context.Context, c *gin.Context, req *CreateReportRequest) (*Report, bool) {
+	report := &Report{TargetType: req.TargetType}
+
+	switch req.TargetType {
+	case reportTargetBlog, reportTargetComment:
+		blogID, ok := objectIDField(c, req.BlogID, "blog_id")
+		if !ok {
+			return nil, false
+		}
+		blog, ok := findVisibleBlog(ctx, c, blogID)
+		if !ok {
+			return nil, false
+		}
+		if blog.HiddenAt != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return nil, false
+		}
+		report.BlogID = &blogID
+
+		if req.TargetType == reportTargetBlog {
+			report.TargetKey = "blog:" + blogID.Hex()
+			report.AuthorID = blog.AuthorID
+			report.Content = snapshot(blog.Title + "\n\n" + blog.Description)
+			return report, true
+		}
+
+		commentID, ok := objectIDField(c, req.CommentID, "comment_id")
+		if !ok {
+			return nil, false
+		}
+		comment := findComment(blog, commentID)
+		if comment == nil || comment.HiddenAt != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return nil, false
+		}
+		report.CommentID = &commentID
+		report.TargetKey = "comment:" + blogID.Hex() + ":" + commentID.Hex()
+		report.AuthorID = comment.UserID
+		report.Content = snapshot(comment.Text)
+		return report, true
+
+	default:
+		tourID, ok := objectIDField(c, req.TourID, "tour_id")
+		if !ok {
+			return nil, false
+		}
+		if req.ReviewUserID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing review_user_id"})
+			return nil, false
+		}
+
+		var tour models.Tour
+		err := toursCollection.FindOne(ctx,
+			bson.M{"_id": tourID},
+			options.FindOne().SetProjection(bson.M{"reviews": 1}),
+		).Decode(&tour)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Printf("Error finding reported review: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return nil, false
+		}
+		for _, review := range tour.Reviews {
+			if review.UserID == req.ReviewUserID && review.HiddenAt == nil {
+				report.TourID = &tourID
+				report.ReviewUserID = &req.ReviewUserID
+				report.TargetKey = fmt.Sprintf("review:%s:%d", tourID.Hex(), req.ReviewUserID)
+				report.AuthorID = review.UserID
+				report.Content = snapshot(review.Comment)
+				return report, true
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+}
+
+// POST /reports - report a blog, comment or tour review for moderation
+func createReport(c *gin.Context) {
+	var req CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report, ok := reportTarget(ctx, c, &req)
+	if !ok {
+		return
+	}
+	userID, _ := auth.UserID(c)
+	if report.AuthorID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own content"})
+		return
+	}
+
+	report.ReporterID = userID
+	report.Reason = req.Reason
+	report.Details = req.Details
+	report.Status = reportStatusOpen
+	report.CreatedAt = time.Now()
+
+	// The partial unique index turns a second open report into a duplicate key error
+	result, err := reportsCollection.InsertOne(ctx, report)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this content"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+		return
+	}
+	report.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Report submitted successfully",
+		"report":  report,
+	})
+}
+
+// GET /admin/reports?status=&target_type=&author_id=&page=&limit= - the
+// moderation queue. Open reports are listed oldest first, resolved ones newest first.
+func getReports(c *gin.Context) {
+	status := c.DefaultQuery("status", reportStatusOpen)
+	switch status {
+	case reportStatusOpen, reportStatusDismissed, reportStatusActioned:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter"})
+		return
+	}
+	filter := bson.M{"status": status}
+
+	if targetType := c.Query("target_type"); targetType != "" {
+		switch targetType {
+		case reportTargetBlog, reportTargetComment, reportTargetReview:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target_type filter"})
+			return
+		}
+		filter["target_type"] = targetType
+	}
+	if authorIDStr := c.Query("author_id"); authorIDStr != "" {
+		authorID, err := strconv.Atoi(authorIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author_id"})
+			return
+		}
+		filter["author_id"] = authorID
+	}
+	page := parsePagination(c, 20, 100)
+
+	sortOrder := -1
+	if status == reportStatusOpen {
+		sortOrder = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	total, err := reportsCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("Error counting reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	cursor, err := reportsCollection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: sortOrder}, {Key: "_id", Value: sortOrder}}).
+		SetSkip(int64(page.Offset())).
+		SetLimit(int64(page.Limit)))
+	if err != nil {
+		log.Printf("Error finding reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	reports := []Report{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		log.Printf("Error decoding reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports":    reports,
+		"pagination": page.Response(total),
+	})
+}
+
+// findOpenReport loads the report in :id, writing 404 or 409 unless it is still open
+func findOpenReport(ctx context.Context, c *gin.Context) (*Report, bool) {
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return nil, false
+	}
+
+	var report Report
+	if err := reportsCollection.FindOne(ctx, bson.M{"_id": reportID}).Decode(&report); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+			return nil, false
+		}
+		log.Printf("Error finding report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+	if report.Status != reportStatusOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "Report is already " + report.Status})
+		return nil, false
+	}
+	return &report, true
+}
+
+// resolveReports closes every open report on the same content as report
+func resolveReports(ctx context.Context, c *gin.Context, report *Report, status string, resolution ReportResolution) {
+	adminID, _ := auth.UserID(c)
+	result, err := reportsCollection.UpdateMany(ctx,
+		bson.M{"target_key": report.TargetKey, "status": reportStatusOpen},
+		bson.M{"$set": bson.M{
+			"status":      status,
+			"resolved_at": time.Now(),
+			"resolved_by": adminID,
+			"resolution":  resolution,
+		}},
+	)
+	if err != nil {
+		log.Printf("Error resolving reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Report " + status + " successfully",
+		"status":         status,
+		"resolution":     resolution,
+		"resolved_count": result.ModifiedCount,
+	})
+}
+
+// hideReportedContent sets hidden_at on the reported blog, comment or review.
+// It returns false when the content no longer exists.
+func hideReportedContent(ctx context.Context, report *Report, now time.Time) (bool, error) {
+	var (
+		result *mongo.UpdateResult
+		err    error
+	)
+	switch report.TargetType {
+	case reportTargetBlog:
+		result, err = blogsCollection.UpdateOne(ctx,
+			bson.M{"_id": report.BlogID},
+			bson.M{"$set": bson.M{"hidden_at": now}},
+		)
+	case reportTargetComment:
+		// A pipeline so comment_count drops in the same write
+		result, err = blogsCollection.UpdateOne(ctx,
+			bson.M{"_id": report.BlogID, "comments._id": report.CommentID},
+			bson.A{
+				bson.M{"$set": bson.M{"comments": bson.M{"$map": bson.M{
+					"input": "$comments",
+					"in": bson.M{"$cond": bson.A{
+						bson.M{"$eq": bson.A{"$$this._id", report.CommentID}},
+						bson.M{"$mergeObjects": bson.A{"$$this", bson.M{"hidden_at": now}}},
+						"$$this",
+					}},
+				}}}},
+				bson.M{"$set": bson.M{"comment_count": visibleCommentCount}},
+			},
+		)
+	case reportTargetReview:
+		result, err = toursCollection.UpdateOne(ctx,
+			bson.M{"_id": report.TourID, "reviews.user_id": report.ReviewUserID},
+			bson.M{"$set": bson.M{"reviews.$[review].hidden_at": now}},
+			options.Update().SetArrayFilters(options.ArrayFilters{
+				Filters: []interface{}{bson.M{"review.user_id": report.ReviewUserID}},
+			}),
+		)
+	default:
+		return false, fmt.Errorf("unknown report target type %q", report.TargetType)
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// POST /admin/reports/:id/dismiss - close the reports on an item without action
+func dismissReport(c *gin.Context) {
+	// The body is optional, it only carries a note
+	var req DismissReportRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report, ok := findOpenReport(ctx, c)
+	if !ok {
+		return
+	}
+	resolveReports(ctx, c, report, reportStatusDismissed, ReportResolution{Note: req.Note})
+}
+
+// POST /admin/reports/:id/action - hide the reported content and/or block
+// its author, then close the reports on it. The content is hidden first, a
+// failed block leaves the reports open so the action can be repeated.
+func actionReport(c *gin.Context) {
+	var req ActionReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Hide && !req.BlockAuthor {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Choose hide, block_author or both, or dismiss the report"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	report, ok := findOpenReport(ctx, c)
+	if !ok {
+		return
+	}
+	resolution := ReportResolution{Note: req.Note}
+
+	if req.Hide {
+		found, err := hideReportedContent(ctx, report, time.Now())
+		if err != nil {
+			log.Printf("Error hiding reported %s: %v", report.TargetType, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hide content"})
+			return
+		}
+		if !found && !req.BlockAuthor {
+			c.JSON(http.StatusConflict, gin.H{"error": "Reported content no longer exists, dismiss the report or block the author"})
+			return
+		}
+		resolution.ContentHidden = found
+	}
+
+	if req.BlockAuthor {
+		reason := req.Note
+		if len(reason) < 3 {
+			reason = "Reported " + report.TargetType + ": " + report.Reason
+		}
+		err := stakeholdersClient.BlockUser(ctx, c.GetHeader("Authorization"), report.AuthorID, reason)
+		if err != nil {
+			log.Printf("Error blocking author %d of reported %s: %v", report.AuthorID, report.TargetType, err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":          "Failed to block the author, the report stays open",
+				"content_hidden": resolution.ContentHidden,
+			})
+			return
+		}
+		resolution.AuthorBlocked = true
+	}
+
+	resolveReports(ctx, c, report, reportStatusActioned, resolution)
+}
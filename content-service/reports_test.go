@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"content-service/auth"
+	"content-service/clients"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+const reportsNS = "soa_tours_content.reports"
+
+// reportsRouter serves the report routes as the given user, without JWTs
+func reportsRouter(userID int, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(auth.ContextUserID, userID)
+		c.Set(auth.ContextRole, role)
+	})
+	router.POST("/reports", createReport)
+	router.POST("/admin/reports/:id/dismiss", dismissReport)
+	router.POST("/admin/reports/:id/action", actionReport)
+	return router
+}
+
+func serveJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(http.MethodPost, path, nil)
+	} else {
+		req = httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer admin-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// useMockCollections points the report handlers at the mocked deployment
+func useMockCollections(mt *mtest.T) {
+	reportsCollection = mt.DB.Collection("reports")
+	blogsCollection = mt.DB.Collection("blogs")
+	toursCollection = mt.DB.Collection("tours")
+}
+
+func openReportDoc(id primitive.ObjectID, targetType string, blogID, commentID primitive.ObjectID) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "target_type", Value: targetType},
+		{Key: "target_key", Value: "comment:" + blogID.Hex() + ":" + commentID.Hex()},
+		{Key: "blog_id", Value: blogID},
+		{Key: "comment_id", Value: commentID},
+		{Key: "author_id", Value: 7},
+		{Key: "reporter_id", Value: 3},
+		{Key: "reason", Value: "spam"},
+		{Key: "status", Value: reportStatusOpen},
+		{Key: "created_at", Value: time.Now()},
+	}
+}
+
+func commandNames(mt *mtest.T) []string {
+	var names []string
+	for _, event := range mt.GetAllStartedEvents() {
+		names = append(names, event.CommandName)
+	}
+	return names
+}
+
+func TestSnapshot(t *testing.T) {
+	if got := snapshot("short"); got != "short" {
+		t.Errorf("snapshot(short) = %q", got)
+	}
+	exact := strings.Repeat("ä", reportSnapshotLength)
+	if got := snapshot(exact); got != exact {
+		t.Errorf("snapshot of %d runes was truncated", reportSnapshotLength)
+	}
+	got := snapshot(strings.Repeat("ä", reportSnapshotLength+1))
+	if want := strings.Repeat("ä", reportSnapshotLength) + "…"; got != want {
+		t.Errorf("snapshot kept %d runes, want %d and an ellipsis", len([]rune(got)), reportSnapshotLength)
+	}
+}
+
+func TestCreateReport(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	blogID := primitive.NewObjectID()
+	blogNS := "soa_tours_content.blogs"
+	blog := bson.D{
+		{Key: "_id", Value: blogID},
+		{Key: "title", Value: "Title"},
+		{Key: "description", Value: "Body"},
+		{Key: "author_id", Value: 7},
+		{Key: "status", Value: blogStatusPublished},
+	}
+
+	mt.Run("rejects invalid requests", func(mt *mtest.T) {
+		useMockCollections(mt)
+		router := reportsRouter(3, auth.RoleTourist)
+		for _, body := range []string{
+			`{}`,
+			`{"target_type": "user", "reason": "spam"}`,
+			`{"target_type": "blog", "reason": "boring"}`,
+			`{"target_type": "blog", "reason": "spam"}`,
+			`{"target_type": "blog", "reason": "spam", "blog_id": "nope"}`,
+			`{"target_type": "review", "reason": "spam", "tour_id": "` + blogID.Hex() + `"}`,
+		} {
+			if w := serveJSON(router, "/reports", body); w.Code != http.StatusBadRequest {
+				mt.Errorf("body %s: status %d, want 400", body, w.Code)
+			}
+		}
+		if names := commandNames(mt); len(names) != 0 {
+			mt.Errorf("invalid requests ran %v", names)
+		}
+	})
+
+	mt.Run("rejects own content", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, blogNS, mtest.FirstBatch, blog))
+
+		w := serveJSON(reportsRouter(7, auth.RoleGuide), "/reports",
+			`{"target_type": "blog", "reason": "spam", "blog_id": "`+blogID.Hex()+`"}`)
+		if w.Code != http.StatusBadRequest {
+			mt.Errorf("status %d, want 400", w.Code)
+		}
+	})
+
+	mt.Run("stores an open report", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, blogNS, mtest.FirstBatch, blog),
+			mtest.CreateSuccessResponse(),
+		)
+
+		w := serveJSON(reportsRouter(3, auth.RoleTourist), "/reports",
+			`{"target_type": "blog", "reason": "spam", "blog_id": "`+blogID.Hex()+`"}`)
+		if w.Code != http.StatusCreated {
+			mt.Fatalf("status %d, want 201: %s", w.Code, w.Body)
+		}
+		var response struct {
+			Report Report `json:"report"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Report.Status != reportStatusOpen || response.Report.AuthorID != 7 || response.Report.ReporterID != 3 {
+			mt.Errorf("report = %+v", response.Report)
+		}
+		if response.Report.Content != "Title\n\nBody" {
+			mt.Errorf("content snapshot = %q", response.Report.Content)
+		}
+	})
+
+	mt.Run("rejects a second open report", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, blogNS, mtest.FirstBatch, blog),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}),
+		)
+
+		w := serveJSON(reportsRouter(3, auth.RoleTourist), "/reports",
+			`{"target_type": "blog", "reason": "spam", "blog_id": "`+blogID.Hex()+`"}`)
+		if w.Code != http.StatusConflict {
+			mt.Errorf("status %d, want 409", w.Code)
+		}
+	})
+}
+
+func TestDismissReport(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	reportID := primitive.NewObjectID()
+	report := openReportDoc(reportID, reportTargetComment, primitive.NewObjectID(), primitive.NewObjectID())
+	path := "/admin/reports/" + reportID.Hex() + "/dismiss"
+
+	mt.Run("without a body", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch, report),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+		)
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, "")
+		if w.Code != http.StatusOK {
+			mt.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+		}
+		var response struct {
+			Status        string `json:"status"`
+			ResolvedCount int    `json:"resolved_count"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Status != reportStatusDismissed || response.ResolvedCount != 2 {
+			mt.Errorf("response = %+v", response)
+		}
+	})
+
+	mt.Run("with a note", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch, report),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, `{"note": "Not spam"}`)
+		if w.Code != http.StatusOK {
+			mt.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), `"note":"Not spam"`) {
+			mt.Errorf("note missing from resolution: %s", w.Body)
+		}
+	})
+
+	mt.Run("already resolved", func(mt *mtest.T) {
+		useMockCollections(mt)
+		resolved := append(bson.D{}, report...)
+		for i := range resolved {
+			if resolved[i].Key == "status" {
+				resolved[i].Value = reportStatusActioned
+			}
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch, resolved))
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, "")
+		if w.Code != http.StatusConflict {
+			mt.Errorf("status %d, want 409", w.Code)
+		}
+		if names := commandNames(mt); len(names) != 1 || names[0] != "find" {
+			mt.Errorf("resolved report ran %v, want only find", names)
+		}
+	})
+
+	mt.Run("missing report", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch))
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, "")
+		if w.Code != http.StatusNotFound {
+			mt.Errorf("status %d, want 404", w.Code)
+		}
+	})
+}
+
+func TestActionReport(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	reportID := primitive.NewObjectID()
+	report := openReportDoc(reportID, reportTargetComment, primitive.NewObjectID(), primitive.NewObjectID())
+	path := "/admin/reports/" + reportID.Hex() + "/action"
+
+	var blockCalls []string
+	blockStatus := http.StatusOK
+	stakeholders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blockCalls = append(blockCalls, r.URL.Path+" "+r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(blockStatus)
+		w.Write([]byte(`{"is_blocked": true}`))
+	}))
+	defer stakeholders.Close()
+	stakeholdersClient = clients.NewStakeholdersClient(stakeholders.URL)
+
+	mt.Run("requires an action", func(mt *mtest.T) {
+		useMockCollections(mt)
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, `{"note": "hmm"}`)
+		if w.Code != http.StatusBadRequest {
+			mt.Errorf("status %d, want 400", w.Code)
+		}
+	})
+
+	mt.Run("hides the comment", func(mt *mtest.T) {
+		useMockCollections(mt)
+		blockCalls = nil
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch, report),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, `{"hide": true}`)
+		if w.Code != http.StatusOK {
+			mt.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), `"content_hidden":true`) ||
+			!strings.Contains(w.Body.String(), `"author_blocked":false`) {
+			mt.Errorf("resolution = %s", w.Body)
+		}
+		if len(blockCalls) != 0 {
+			mt.Errorf("hide only called stakeholders: %v", blockCalls)
+		}
+	})
+
+	mt.Run("gone content without a block", func(mt *mtest.T) {
+		useMockCollections(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch, report),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, `{"hide": true}`)
+		if w.Code != http.StatusConflict {
+			mt.Errorf("status %d, want 409", w.Code)
+		}
+		if names := commandNames(mt); len(names) != 2 {
+			mt.Errorf("ran %v, want find and the hide update only", names)
+		}
+	})
+
+	mt.Run("blocks the author", func(mt *mtest.T) {
+		useMockCollections(mt)
+		blockCalls = nil
+		blockStatus = http.StatusOK
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch, report),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, `{"block_author": true}`)
+		if w.Code != http.StatusOK {
+			mt.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), `"author_blocked":true`) {
+			mt.Errorf("resolution = %s", w.Body)
+		}
+		want := "/admin/users/7/block Bearer admin-token"
+		if len(blockCalls) != 1 || blockCalls[0] != want {
+			mt.Errorf("block calls = %v, want [%s]", blockCalls, want)
+		}
+	})
+
+	mt.Run("failed block keeps the report open", func(mt *mtest.T) {
+		useMockCollections(mt)
+		blockCalls = nil
+		blockStatus = http.StatusForbidden
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, reportsNS, mtest.FirstBatch, report),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		w := serveJSON(reportsRouter(1, auth.RoleAdmin), path, `{"hide": true, "block_author": true}`)
+		if w.Code != http.StatusBadGateway {
+			mt.Fatalf("status %d, want 502: %s", w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), `"content_hidden":true`) {
+			mt.Errorf("response = %s", w.Body)
+		}
+		// find and the hide update, the reports are not resolved
+		if names := commandNames(mt); len(names) != 2 {
+			mt.Errorf("ran %v, want find and the hide update only", names)
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"html"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"content-service/markdown"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Blog search runs on the text index over title and description. Snippets are
+// HTML-escaped with the matched words wrapped in <mark>.
+
+const (
+	snippetLength = 200
+	maxQueryTerms = 10
+)
+
+var searchSorts = map[string]bson.D{
+	"relevance":  {{Key: "score", Value: -1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	"newest":     {{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	"most_liked": {{Key: "like_count", Value: -1}, {Key: "score", Value: -1}, {Key: "_id", Value: -1}},
+}
+
+// BlogSearchResult is a matching blog with its relevance and highlights
+type BlogSearchResult struct {
+	Blog           `bson:",inline"`
+	Score          float64 `json:"score" bson:"score"`
+	TitleHighlight string  `json:"title_highlight" bson:"-"`
+	Snippet        string  `json:"snippet" bson:"-"`
+}
+
+// parseSearchDate accepts 2006-01-02 or RFC 3339, a bare date as upper bound means the whole day
+func parseSearchDate(value string, endOfDay bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, true
+}
+
+// searchTerms extracts the words to highlight, phrases count word by word and
+// excluded (-word) terms are skipped
+func searchTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(strings.ReplaceAll(query, `"`, " ")) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		field = strings.Trim(field, ".,;:!?()[]{}'")
+		if field == "" {
+			continue
+		}
+		terms = append(terms, regexp.QuoteMeta(field))
+		if len(terms) == maxQueryTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// highlightPattern matches words starting with a term, so "tour" also marks
+// "tours" the way the stemmed text index matched it. \b only knows ASCII, so
+// the preceding character is matched instead and group 1 is the word.
+func highlightPattern(terms []string) *regexp.Regexp {
+	if len(terms) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_])((?:` + strings.Join(terms, "|") + `)[\p{L}\p{N}_]*)`)
+}
+
+// matchedWords returns the [start, end) offsets of the highlighted words
+func matchedWords(text string, pattern *regexp.Regexp) [][2]int {
+	var words [][2]int
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		words = append(words, [2]int{loc[2], loc[3]})
+	}
+	return words
+}
+
+// highlight escapes text and wraps every match in <mark>
+func highlight(text string, pattern *regexp.Regexp) string {
+	if pattern == nil {
+		return html.EscapeString(text)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range matchedWords(text, pattern) {
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// snippet cuts about snippetLength characters around the first match of text
+func snippet(text string, pattern *regexp.Regexp) string {
+	text = strings.Join(strings.Fields(text), " ")
+	start := 0
+	if pattern != nil {
+		if words := matchedWords(text, pattern); len(words) > 0 && words[0][0] > snippetLength/4 {
+			start = words[0][0] - snippetLength/4
+		}
+	}
+	// Start and end on word boundaries and never inside a UTF-8 sequence
+	if start > 0 {
+		if space := strings.IndexByte(text[start:], ' '); space >= 0 {
+			start += space + 1
+		}
+	}
+	end := start
+	for count := 0; end < len(text) && count < snippetLength; count++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	if end < len(text) {
+		if space := strings.LastIndexByte(text[start:end], ' '); space > 0 {
+			end = start + space
+		}
+	}
+
+	result := highlight(text[start:end], pattern)
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(text) {
+		result += "…"
+	}
+	return result
+}
+
+// GET /blogs/search?q=&author_id=&from=&to=&sort=&page=&limit= - full-text
+// search over published and closed blogs (and the caller's drafts). sort is
+// relevance (default), newest or most_liked.
+func searchBlogs(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+	if len(query) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query is too long"})
+		return
+	}
+
+	sortKey := c.DefaultQuery("sort", "relevance")
+	sort, ok := searchSorts[sortKey]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use relevance, newest or most_liked"})
+		return
+	}
+
+	// $text has to be in the first stage, visibility and filters join it there
+	match := bson.M{
+		"$text": bson.M{"$search": query},
+		"$and":  bson.A{visibleBlogsFilter(c)},
+	}
+	if authorIDStr := c.Query("author_id"); authorIDStr != "" {
+		authorID, err := strconv.Atoi(authorIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+			return
+		}
+		match["author_id"] = authorID
+	}
+	createdAt := bson.M{}
+	if from := c.Query("from"); from != "" {
+		t, ok := parseSearchDate(from, false)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD or RFC 3339"})
+			return
+		}
+		createdAt["$gte"] = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, ok := parseSearchDate(to, true)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD or RFC 3339"})
+			return
+		}
+		createdAt["$lte"] = t
+	}
+	if len(createdAt) > 0 {
+		match["created_at"] = createdAt
+	}
+
+	page := parsePagination(c, 10, 50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := blogsCollection.Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}},
+		bson.M{"$project": blogListProjection},
+		bson.M{"$sort": sort},
+		bson.M{"$facet": bson.M{
+			"total":   bson.A{bson.M{"$count": "count"}},
+			"results": bson.A{bson.M{"$skip": page.Offset()}, bson.M{"$limit": page.Limit}},
+		}},
+	})
+	if err != nil {
+		log.Printf("Error searching blogs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Results []BlogSearchResult `bson:"results"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		log.Printf("Error decoding search results: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	results := []BlogSearchResult{}
+	var total int64
+	if len(facets) > 0 {
+		if facets[0].Results != nil {
+			results = facets[0].Results
+		}
+		if len(facets[0].Total) > 0 {
+			total = facets[0].Total[0].Count
+		}
+	}
+
+	pattern := highlightPattern(searchTerms(query))
+	refs := make([]*Blog, len(results))
+	for i := range results {
+		results[i].TitleHighlight = highlight(results[i].Title, pattern)
+		results[i].Snippet = snippet(markdown.PlainText(results[i].Description), pattern)
+		refs[i] = &results[i].Blog
+	}
+	setLikedFlags(c, refs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":      query,
+		"sort":       sortKey,
+		"results":    results,
+		"pagination": page.Response(total),
+	})
+}
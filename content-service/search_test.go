@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"tour belgrade", []string{"tour", "belgrade"}},
+		{`"old town" -museum`, []string{"old", "town"}},
+		{"(c++)?", []string{`c\+\+`}},
+		{"- -- ...", nil},
+	}
+	for _, tt := range tests {
+		if got := searchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		text  string
+		want  string
+	}{
+		{"prefix match", "tour", "Tours of the old town", "<mark>Tours</mark> of the old town"},
+		{"several terms", "old town", "the old town", "the <mark>old</mark> <mark>town</mark>"},
+		{"not inside a word", "tour", "detour", "detour"},
+		{"non ASCII words", "čaj", "Dobar čaj, čajnik", "Dobar <mark>čaj</mark>, <mark>čajnik</mark>"},
+		{"text is escaped", "tour", "<b>tour</b> & more", "&lt;b&gt;<mark>tour</mark>&lt;/b&gt; &amp; more"},
+		{"no terms", "-tour", "<tour>", "&lt;tour&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlight(tt.text, highlightPattern(searchTerms(tt.query)))
+			if got != tt.want {
+				t.Errorf("highlight = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	pattern := highlightPattern(searchTerms("lake"))
+	filler := strings.Repeat("word ", 100)
+
+	short := snippet("A walk   around the\nlake.", pattern)
+	if want := "A walk around the <mark>lake</mark>."; short != want {
+		t.Errorf("short snippet = %q, want %q", short, want)
+	}
+
+	long := snippet(filler+"the lake "+filler, pattern)
+	if !strings.HasPrefix(long, "…word") || !strings.HasSuffix(long, "word…") {
+		t.Errorf("long snippet is not cut on both sides at word boundaries: %q", long)
+	}
+	if !strings.Contains(long, "the <mark>lake</mark>") {
+		t.Errorf("long snippet misses the match: %q", long)
+	}
+	if n := len([]rune(strings.NewReplacer("<mark>", "", "</mark>", "", "…", "").Replace(long))); n > snippetLength {
+		t.Errorf("long snippet has %d characters, want at most %d", n, snippetLength)
+	}
+
+	start := snippet("lake "+filler, pattern)
+	if !strings.HasPrefix(start, "<mark>lake</mark> word") || strings.HasPrefix(start, "…") {
+		t.Errorf("snippet of an early match does not start at the text: %q", start)
+	}
+
+	multibyte := snippet(strings.Repeat("ž", 300), nil)
+	if !strings.HasSuffix(multibyte, "…") || !strings.HasPrefix(multibyte, "ž") {
+		t.Errorf("multibyte snippet = %q", multibyte)
+	}
+}
@@ -37,7 +37,15 @@ db.createCollection('blogs', {
                 description: {
                     bsonType: "string",
                     minLength: 1,
-                    description: "Blog content - required string"
+                    description: "Blog content in Markdown - required string"
+                },
+                description_html: {
+                    bsonType: "string",
+                    description: "Sanitized HTML rendered from the description"
+                },
+                excerpt: {
+                    bsonType: "string",
+                    description: "Plain-text excerpt for list views"
                 },
                 author_id: {
                     bsonType: "int",
@@ -51,27 +59,39 @@ db.createCollection('blogs', {
                     },
                     description: "Array of image URLs"
                 },
-                likes: {
-                    bsonType: "array",
-                    items: {
-                        bsonType: "int"
-                    },
-                    description: "Array of user IDs who liked the blog"
+                like_count: {
+                    bsonType: "int",
+                    minimum: 0,
+                    description: "Number of likes, the likes themselves are in blog_likes"
                 },
                 comments: {
                     bsonType: "array",
                     items: {
                         bsonType: "object",
-                        required: ["user_id", "text", "created_at"],
+                        required: ["_id", "user_id", "text", "created_at"],
                         properties: {
+                            _id: { bsonType: "objectId" },
                             user_id: { bsonType: "int" },
                             text: { bsonType: "string", minLength: 1 },
+                            parent_id: { bsonType: "objectId" },
+                            depth: { bsonType: "int", minimum: 0, maximum: 4 },
+                            mentions: { bsonType: "array", items: { bsonType: "int" } },
                             created_at: { bsonType: "date" },
-                            updated_at: { bsonType: "date" }
+                            updated_at: { bsonType: "date" },
+                            hidden_at: { bsonType: "date" }
                         }
                     },
                     description: "Array of comment objects"
                 },
+                comment_count: {
+                    bsonType: "int",
+                    minimum: 0,
+                    description: "Number of comments, kept in sync with the array"
+                },
+                status: {
+                    enum: ["draft", "published", "closed"],
+                    description: "Drafts are visible to the author only, closed blogs take no comments or likes"
+                },
                 created_at: {
                     bsonType: "date",
                     description: "Creation timestamp"
@@ -176,7 +196,8 @@ db.createCollection('tours', {
                             comment: { bsonType: "string" },
                             visit_date: { bsonType: "date" },
                             created_at: { bsonType: "date" },
-                            images: { bsonType: "array", items: { bsonType: "string" } }
+                            images: { bsonType: "array", items: { bsonType: "string" } },
+                            hidden_at: { bsonType: "date" }
                         }
                     },
                     description: "Array of tour reviews"
@@ -310,14 +331,128 @@ db.createCollection('positions', {
     }
 });
 
+// Create blog_likes collection (one document per user and liked blog)
+print("Creating blog_likes collection...");
+db.createCollection('blog_likes', {
+    validator: {
+        $jsonSchema: {
+            bsonType: "object",
+            required: ["blog_id", "user_id", "created_at"],
+            properties: {
+                blog_id: {
+                    bsonType: "objectId",
+                    description: "Liked blog"
+                },
+                user_id: {
+                    bsonType: "int",
+                    minimum: 1,
+                    description: "User who liked the blog"
+                },
+                created_at: {
+                    bsonType: "date",
+                    description: "When the like was given, used for trending"
+                }
+            }
+        }
+    }
+});
+
+// Create media collection (uploaded images, files live in the media storage)
+print("Creating media collection...");
+db.createCollection('media', {
+    validator: {
+        $jsonSchema: {
+            bsonType: "object",
+            required: ["owner_id", "purpose", "key", "thumbnail_key", "content_type", "created_at"],
+            properties: {
+                owner_id: {
+                    bsonType: "int",
+                    minimum: 1,
+                    description: "User who uploaded the file"
+                },
+                purpose: {
+                    enum: ["profile", "blog", "keypoint", "review"],
+                    description: "Where the image is used"
+                },
+                key: {
+                    bsonType: "string",
+                    description: "Storage key of the original image"
+                },
+                thumbnail_key: {
+                    bsonType: "string",
+                    description: "Storage key of the thumbnail"
+                },
+                content_type: {
+                    enum: ["image/jpeg", "image/png", "image/gif", "image/webp"],
+                    description: "Sniffed image type"
+                },
+                size: {
+                    bsonType: "int",
+                    minimum: 0,
+                    description: "Size of the original in bytes"
+                },
+                created_at: {
+                    bsonType: "date",
+                    description: "Upload timestamp"
+                }
+            }
+        }
+    }
+});
+
+// Create reports collection (moderation queue for blogs, comments and tour reviews)
+print("Creating reports collection...");
+db.createCollection('reports', {
+    validator: {
+        $jsonSchema: {
+            bsonType: "object",
+            required: ["target_type", "target_key", "author_id", "reporter_id", "reason", "status", "created_at"],
+            properties: {
+                target_type: {
+                    enum: ["blog", "comment", "review"],
+                    description: "Kind of reported content"
+                },
+                target_key: {
+                    bsonType: "string",
+                    description: "Identifies the reported item, shared by all its reports"
+                },
+                author_id: {
+                    bsonType: "int",
+                    description: "Author of the reported content"
+                },
+                reporter_id: {
+                    bsonType: "int",
+                    minimum: 1,
+                    description: "User who filed the report"
+                },
+                reason: {
+                    enum: ["spam", "harassment", "hate_speech", "inappropriate", "misinformation", "other"],
+                    description: "Why the content was reported"
+                },
+                status: {
+                    enum: ["open", "dismissed", "actioned"],
+                    description: "Open reports wait in the moderation queue"
+                },
+                created_at: {
+                    bsonType: "date",
+                    description: "Report timestamp"
+                }
+            }
+        }
+    }
+});
+
 // Create indexes for better performance
 print("Creating database indexes...");
 
 // Blogs indexes
 db.blogs.createIndex({ "author_id": 1 });
 db.blogs.createIndex({ "created_at": -1 });
+db.blogs.createIndex({ "status": 1, "created_at": -1 });
 db.blogs.createIndex({ "title": "text", "description": "text" }); // Text search
-db.blogs.createIndex({ "likes": 1 }); // For like queries
+db.blogs.createIndex({ "like_count": -1 }); // Most liked
+db.blogs.createIndex({ "author_id": 1, "created_at": -1, "_id": -1 }); // Followed authors feed
+// The deleted_at index is created by content-service, which also purges deleted blogs
 
 // Tours indexes
 db.tours.createIndex({ "author_id": 1 });
@@ -344,6 +479,23 @@ db.positions.createIndex({ "user_id": 1 });
 db.positions.createIndex({ "timestamp": -1 });
 db.positions.createIndex({ "user_id": 1, "timestamp": -1 }); // Compound index
 
+// Blog likes indexes, content-service ensures the same ones on startup
+db.blog_likes.createIndex({ "blog_id": 1, "user_id": 1 }, { unique: true });
+db.blog_likes.createIndex({ "blog_id": 1, "created_at": -1 });
+db.blog_likes.createIndex({ "user_id": 1 });
+db.blog_likes.createIndex({ "created_at": -1 }); // Trending window
+
+// Media indexes
+db.media.createIndex({ "owner_id": 1, "created_at": -1 });
+db.media.createIndex({ "key": 1 }, { unique: true });
+
+// Reports indexes, content-service ensures the same ones on startup
+db.reports.createIndex({ "target_key": 1, "reporter_id": 1 }, { unique: true, partialFilterExpression: { status: "open" } });
+db.reports.createIndex({ "status": 1, "created_at": 1 });
+db.reports.createIndex({ "target_key": 1, "status": 1 });
+db.reports.createIndex({ "reporter_id": 1 });
+db.reports.createIndex({ "author_id": 1 });
+
 // Insert sample data
 print("Inserting sample data...");
 
@@ -352,16 +504,19 @@ const sampleBlog = {
     title: "Welcome to SOA Tours",
     description: "This is a sample blog post about our new microservices-based tour platform. We're excited to share amazing travel experiences with you!",
     author_id: 2, // guide1
+    status: "published",
     images: [],
-    likes: [3], // tourist1 liked it
+    like_count: 1, // tourist1 liked it, see blog_likes below
     comments: [
         {
+            _id: new ObjectId(),
             user_id: 3,
             text: "Great introduction! Looking forward to trying the tours.",
             created_at: new Date(),
             updated_at: new Date()
         }
     ],
+    comment_count: 1,
     created_at: new Date(),
     updated_at: new Date()
 };
@@ -369,6 +524,12 @@ const sampleBlog = {
 const blogResult = db.blogs.insertOne(sampleBlog);
 print(`Inserted sample blog with ID: ${blogResult.insertedId}`);
 
+db.blog_likes.insertOne({
+    blog_id: blogResult.insertedId,
+    user_id: 3,
+    created_at: new Date()
+});
+
 // Sample tour
 const sampleTour = {
     name: "Historic Downtown Walking Tour",
@@ -15,6 +15,13 @@ DEFAULT COLLATE utf8mb4_unicode_ci;
 USE soa_tours;
 
 -- Drop tables if they exist (for clean reinitialization)
+DROP TABLE IF EXISTS admin_audit_log;
+DROP TABLE IF EXISTS login_lockouts;
+DROP TABLE IF EXISTS login_failures;
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS account_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
 DROP TABLE IF EXISTS purchase_tokens;
 DROP TABLE IF EXISTS cart_items;
 DROP TABLE IF EXISTS shopping_carts;
@@ -29,6 +36,12 @@ CREATE TABLE users (
     password_hash VARCHAR(255) NOT NULL,
     role ENUM('admin', 'guide', 'tourist') NOT NULL DEFAULT 'tourist',
     is_blocked BOOLEAN DEFAULT FALSE,
+    email_verified BOOLEAN DEFAULT FALSE, -- Unverified accounts cannot comment or purchase
+    email_verified_at TIMESTAMP NULL,
+    totp_secret VARCHAR(64) NULL, -- Base32 TOTP secret, pending until totp_enabled
+    totp_enabled BOOLEAN DEFAULT FALSE,
+    totp_last_step BIGINT NULL, -- Last accepted TOTP time step, blocks code replay
+    deleted_at TIMESTAMP NULL, -- Soft delete by admin
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
@@ -157,10 +170,124 @@ CREATE TABLE blog_comments (
     INDEX idx_created_at (created_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- Refresh tokens table - Login sessions issued by stakeholders service
+CREATE TABLE refresh_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the opaque refresh token
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL, -- Set on logout, rotation, revoke or block
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- Foreign key constraint
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Indexes
+    INDEX idx_user_id (user_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Account tokens table - One-time email verification and password reset tokens
+CREATE TABLE account_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    purpose ENUM('verify_email', 'reset_password') NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the mailed token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL, -- Set when consumed or replaced by a newer token
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- Foreign key constraint
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Indexes
+    INDEX idx_user_purpose (user_id, purpose)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Recovery codes table - One-time 2FA backup codes
+CREATE TABLE recovery_codes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL, -- SHA-256 of the normalized code
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- Foreign key constraint
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Indexes
+    UNIQUE KEY unique_user_code (user_id, code_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Login challenges table - Second login step of users with 2FA enabled
+CREATE TABLE login_challenges (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the challenge token
+    attempts INT NOT NULL DEFAULT 0, -- Wrong codes entered, capped by the service
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- Foreign key constraint
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    
+    -- Indexes
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Admin audit log - Every admin user management action
+CREATE TABLE admin_audit_log (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    admin_id INT NOT NULL,
+    target_user_id INT NOT NULL,
+    action VARCHAR(50) NOT NULL, -- block_user, unblock_user, change_role, delete_user, revoke_sessions, unlock_login
+    reason VARCHAR(255),
+    details JSON NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- No foreign keys, entries must outlive the users they reference
+    
+    -- Indexes
+    INDEX idx_admin_id (admin_id),
+    INDEX idx_target_user_id (target_user_id),
+    INDEX idx_action (action),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Login failures table - Failed login counters per username and per client IP
+-- (usernames that do not exist are counted too), and password reset request
+-- counters per email and IP
+CREATE TABLE login_failures (
+    scope ENUM('username', 'ip', 'reset_email', 'reset_ip') NOT NULL,
+    scope_key VARCHAR(100) NOT NULL, -- Lowercased username or email, or IP address
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP NULL, -- Backoff or lockout end
+    
+    PRIMARY KEY (scope, scope_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Login lockouts table - Lockout events shown to admins
+CREATE TABLE login_lockouts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    scope ENUM('username', 'ip', 'reset_email', 'reset_ip') NOT NULL,
+    scope_key VARCHAR(100) NOT NULL,
+    user_id INT NULL, -- Set for username lockouts of existing accounts
+    ip_address VARCHAR(45) NOT NULL, -- Address of the attempt that caused the lockout
+    failed_count INT NOT NULL,
+    locked_until TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- Indexes
+    INDEX idx_user_id (user_id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- Insert default admin user
 -- Password hash for 'admin123' using bcrypt
-INSERT INTO users (username, email, password_hash, role) VALUES 
-('admin', 'admin@soatours.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'admin');
+INSERT INTO users (username, email, password_hash, role, email_verified, email_verified_at) VALUES 
+('admin', 'admin@soatours.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'admin', TRUE, NOW());
 
 -- Insert admin profile
 INSERT INTO profiles (user_id, first_name, last_name, biography, motto) VALUES 
@@ -170,8 +297,8 @@ INSERT INTO profiles (user_id, first_name, last_name, biography, motto) VALUES
 INSERT INTO shopping_carts (user_id) VALUES (1);
 
 -- Insert sample guide user
-INSERT INTO users (username, email, password_hash, role) VALUES 
-('guide1', 'guide@soatours.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'guide');
+INSERT INTO users (username, email, password_hash, role, email_verified, email_verified_at) VALUES 
+('guide1', 'guide@soatours.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'guide', TRUE, NOW());
 
 INSERT INTO profiles (user_id, first_name, last_name, biography, motto) VALUES 
 (2, 'John', 'Guide', 'Experienced tour guide specializing in historical tours', 'History comes alive through stories');
@@ -179,8 +306,8 @@ INSERT INTO profiles (user_id, first_name, last_name, biography, motto) VALUES
 INSERT INTO shopping_carts (user_id) VALUES (2);
 
 -- Insert sample tourist user
-INSERT INTO users (username, email, password_hash, role) VALUES 
-('tourist1', 'tourist@soatours.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'tourist');
+INSERT INTO users (username, email, password_hash, role, email_verified, email_verified_at) VALUES 
+('tourist1', 'tourist@soatours.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'tourist', TRUE, NOW());
 
 INSERT INTO profiles (user_id, first_name, last_name, biography, motto) VALUES 
 (3, 'Jane', 'Tourist', 'Adventure seeker and travel enthusiast', 'Life is an adventure waiting to happen');
@@ -339,7 +339,7 @@ export class BlogsListComponent implements OnInit {
   errorMessage = signal('');
   selectedBlog = signal<Blog | null>(null);
 
-  private readonly CONTENT_API = 'http://localhost:8082';
+  private readonly CONTENT_API = 'http://localhost:8080/content';
   private currentPage = 1;
 
   constructor(private http: HttpClient) {}
@@ -411,9 +411,8 @@ export class BlogsListComponent implements OnInit {
     const currentUser = this.getCurrentUser();
     const isLiked = this.isLikedByCurrentUser(blog);
     const method = isLiked ? 'DELETE' : 'POST';
-    const headers = { 'X-User-ID': currentUser.id.toString() };
 
-    this.http.request(method, `${this.CONTENT_API}/blogs/${blog.id}/like`, { headers })
+    this.http.request(method, `${this.CONTENT_API}/blogs/${blog.id}/like`)
       .subscribe({
         next: () => {
           // Update local state
@@ -249,7 +249,7 @@ export class CreateBlogComponent {
   errorMessage = signal('');
   successMessage = signal('');
 
-  private readonly CONTENT_API = 'http://localhost:8082';
+  private readonly CONTENT_API = 'http://localhost:8080/content';
 
   constructor(
     private fb: FormBuilder,
@@ -314,14 +314,7 @@ export class CreateBlogComponent {
         images: this.getValidImages()
       };
 
-      // Get current user ID for X-User-ID header
-      const currentUser = this.getCurrentUser();
-      const headersObj: Record<string, string> = {};
-      if (currentUser && currentUser.id) {
-        headersObj['X-User-ID'] = currentUser.id.toString();
-      }
-
-      this.http.post(`${this.CONTENT_API}/blogs`, blogData, { headers: headersObj })
+      this.http.post(`${this.CONTENT_API}/blogs`, blogData)
         .subscribe({
           next: (response: any) => {
             this.isLoading.set(false);
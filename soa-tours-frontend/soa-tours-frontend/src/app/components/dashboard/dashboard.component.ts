@@ -226,13 +226,13 @@ export class DashboardComponent implements OnInit {
 
   endpoints = signal<Endpoint[]>([
     { method: 'GET', url: 'localhost:8080/health', status: false },
-    { method: 'GET', url: 'localhost:8081/health', status: false },
-    { method: 'GET', url: 'localhost:8081/users', status: false },
-    { method: 'GET', url: 'localhost:8082/health', status: false },
-    { method: 'GET', url: 'localhost:8082/blogs', status: false },
-    { method: 'GET', url: 'localhost:8082/tours', status: false },
-    { method: 'GET', url: 'localhost:8083/health', status: false },
-    { method: 'GET', url: 'localhost:8083/cart', status: false }
+    { method: 'GET', url: 'localhost:8080/stakeholders/health', status: false },
+    { method: 'GET', url: 'localhost:8080/stakeholders/users', status: false },
+    { method: 'GET', url: 'localhost:8080/content/health', status: false },
+    { method: 'GET', url: 'localhost:8080/content/blogs', status: false },
+    { method: 'GET', url: 'localhost:8080/content/tours', status: false },
+    { method: 'GET', url: 'localhost:8080/commerce/health', status: false },
+    { method: 'GET', url: 'localhost:8080/commerce/cart', status: false }
   ]);
 
   constructor(private apiService: ApiService) {}
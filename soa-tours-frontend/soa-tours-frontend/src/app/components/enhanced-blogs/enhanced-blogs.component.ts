@@ -207,10 +207,9 @@ export class EnhancedBlogsComponent implements OnInit {
     }
 
   loadFollowingList(): void {
-    this.apiService.getFollowing().subscribe({
+    this.apiService.getFollowingIds().subscribe({
       next: (data) => {
-        const followingIds = (data.following || []).map((f: any) => f.following_id);
-        this.followingList.set(followingIds);
+        this.followingList.set(data.user_ids || []);
       },
       error: (error) => {
         console.error('Failed to load following list:', error);
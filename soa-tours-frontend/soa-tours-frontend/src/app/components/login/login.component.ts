@@ -209,7 +209,7 @@ export class LoginComponent {
   successMessage = signal('');
   showPassword = signal(false);
 
-  private readonly STAKEHOLDERS_API = 'http://localhost:8081';
+  private readonly STAKEHOLDERS_API = 'http://localhost:8080/stakeholders';
 
   constructor(
     private fb: FormBuilder,
@@ -260,6 +260,8 @@ export class LoginComponent {
             
             // Store user data in localStorage
             localStorage.setItem('currentUser', JSON.stringify(response.user));
+            localStorage.setItem('accessToken', response.access_token);
+            localStorage.setItem('refreshToken', response.refresh_token);
             
             // Redirect based on role
             setTimeout(() => {
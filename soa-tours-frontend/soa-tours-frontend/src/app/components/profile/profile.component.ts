@@ -305,7 +305,7 @@ export class ProfileComponent implements OnInit {
   userId: number = 0;
   currentUser: any = null;
 
-  private readonly STAKEHOLDERS_API = 'http://localhost:8081';
+  private readonly STAKEHOLDERS_API = 'http://localhost:8080/stakeholders';
 
   constructor(
     private fb: FormBuilder,
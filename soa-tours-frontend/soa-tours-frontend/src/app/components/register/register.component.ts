@@ -281,7 +281,7 @@ export class RegisterComponent {
   successMessage = signal('');
   showPassword = signal(false);
 
-  private readonly STAKEHOLDERS_API = 'http://localhost:8081';
+  private readonly STAKEHOLDERS_API = 'http://localhost:8080/stakeholders';
 
   constructor(
     private fb: FormBuilder,
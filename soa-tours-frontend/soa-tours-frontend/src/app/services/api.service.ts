@@ -61,6 +61,11 @@ export interface LoginRequest {
 
 export interface LoginResponse {
   message: string;
+  access_token: string;
+  token_type: string;
+  expires_at: string;
+  refresh_token: string;
+  refresh_expires_at: string;
   user: {
     id: number;
     username: string;
@@ -334,9 +339,9 @@ export interface Review {
 })
 export class ApiService {
   private readonly API_BASE = 'http://localhost:8080';
-  private readonly STAKEHOLDERS_API = 'http://localhost:8081';
-  private readonly CONTENT_API = 'http://localhost:8082';
-  private readonly COMMERCE_API = 'http://localhost:8083';
+  private readonly STAKEHOLDERS_API = 'http://localhost:8080/stakeholders';
+  private readonly CONTENT_API = 'http://localhost:8080/content';
+  private readonly COMMERCE_API = 'http://localhost:8080/commerce';
 
   // Angular Signal for reactive service status
   public servicesStatus = signal<ServiceStatus>({
@@ -430,11 +435,7 @@ export class ApiService {
 
   // Blog API calls
   getBlogs(page: number = 1, limit: number = 10, userId?: number): Observable<{blogs: Blog[], pagination: any}> {
-    const currentUserId = userId || this.getCurrentUserId();
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.get<{blogs: Blog[], pagination: any}>(`${this.CONTENT_API}/blogs?page=${page}&limit=${limit}`, { headers });
+    return this.http.get<{blogs: Blog[], pagination: any}>(`${this.CONTENT_API}/blogs?page=${page}&limit=${limit}`);
   }
 
   getBlogById(id: string): Observable<{blog: Blog}> {
@@ -443,8 +444,7 @@ export class ApiService {
 
   createBlog(data: CreateBlogRequest, userId?: number): Observable<any> {
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      ...(userId && { 'X-User-ID': userId.toString() })
+      'Content-Type': 'application/json'
     });
     
     return this.http.post(`${this.CONTENT_API}/blogs`, data, { headers });
@@ -452,38 +452,27 @@ export class ApiService {
 
   updateBlog(id: string, data: UpdateBlogRequest, userId?: number): Observable<any> {
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      ...(userId && { 'X-User-ID': userId.toString() })
+      'Content-Type': 'application/json'
     });
     
     return this.http.put(`${this.CONTENT_API}/blogs/${id}`, data, { headers });
   }
 
   deleteBlog(id: string, userId?: number): Observable<any> {
-    const headers = new HttpHeaders({
-      ...(userId && { 'X-User-ID': userId.toString() })
-    });
-    
-    return this.http.delete(`${this.CONTENT_API}/blogs/${id}`, { headers });
+    return this.http.delete(`${this.CONTENT_API}/blogs/${id}`);
   }
 
   likeBlog(id: string, userId?: number): Observable<any> {
-    const currentUserId = userId || this.getCurrentUserId();
-    const headers = new HttpHeaders({ 'X-User-ID': currentUserId.toString() });
-    return this.http.post(`${this.CONTENT_API}/blogs/${id}/like`, {}, { headers });
+    return this.http.post(`${this.CONTENT_API}/blogs/${id}/like`, {});
   }
 
   unlikeBlog(id: string, userId?: number): Observable<any> {
-    const currentUserId = userId || this.getCurrentUserId();
-    const headers = new HttpHeaders({ 'X-User-ID': currentUserId.toString() });
-    return this.http.delete(`${this.CONTENT_API}/blogs/${id}/like`, { headers });
+    return this.http.delete(`${this.CONTENT_API}/blogs/${id}/like`);
   }
 
   addComment(blogId: string, text: string, userId?: number): Observable<any> {
-  const currentUserId = userId || this.getCurrentUserId();
   const headers = new HttpHeaders({ 
-    'Content-Type': 'application/json',
-    'X-User-ID': currentUserId.toString() 
+    'Content-Type': 'application/json'
   });
   return this.http.post(`${this.CONTENT_API}/blogs/${blogId}/comments`, { text }, { headers });
 
@@ -501,10 +490,8 @@ export class ApiService {
 
   // Tour methods
 createTour(data: CreateTourRequest, userId?: number): Observable<any> {
-  const currentUserId = userId || this.getCurrentUserId();
   const headers = new HttpHeaders({
-    'Content-Type': 'application/json',
-    'X-User-ID': currentUserId.toString()
+    'Content-Type': 'application/json'
   });
   return this.http.post(`${this.CONTENT_API}/tours`, data, { headers });
 }
@@ -514,10 +501,7 @@ getTours(authorId?: number): Observable<any> {
   if (authorId) {
     url += `?author_id=${authorId}`;
   }
-  const headers = new HttpHeaders({
-    'X-User-ID': (authorId || this.getCurrentUserId()).toString()
-  });
-  return this.http.get(url, { headers });
+  return this.http.get(url);
 }
 
 getTourById(id: string): Observable<any> {
@@ -525,29 +509,21 @@ getTourById(id: string): Observable<any> {
 }
 
 updateTour(id: string, data: UpdateTourRequest, userId?: number): Observable<any> {
-  const currentUserId = userId || this.getCurrentUserId();
   const headers = new HttpHeaders({
-    'Content-Type': 'application/json',
-    'X-User-ID': currentUserId.toString()
+    'Content-Type': 'application/json'
   });
   return this.http.put(`${this.CONTENT_API}/tours/${id}`, data, { headers });
 }
 
 addKeypoint(tourId: string, data: AddKeypointRequest, userId?: number): Observable<any> {
-  const currentUserId = userId || this.getCurrentUserId();
   const headers = new HttpHeaders({
-    'Content-Type': 'application/json',
-    'X-User-ID': currentUserId.toString()
+    'Content-Type': 'application/json'
   });
   return this.http.post(`${this.CONTENT_API}/tours/${tourId}/keypoints`, data, { headers });
 }
 
 removeKeypoint(tourId: string, order: number, userId?: number): Observable<any> {
-  const currentUserId = userId || this.getCurrentUserId();
-  const headers = new HttpHeaders({
-    'X-User-ID': currentUserId.toString()
-  });
-  return this.http.delete(`${this.CONTENT_API}/tours/${tourId}/keypoints/${order}`, { headers });
+  return this.http.delete(`${this.CONTENT_API}/tours/${tourId}/keypoints/${order}`);
 }
 
   // Test all endpoints
@@ -616,65 +592,57 @@ removeKeypoint(tourId: string, order: number, userId?: number): Observable<any>
   }
 
   logout(): void {
+    const refreshToken = localStorage.getItem('refreshToken');
+    if (refreshToken) {
+      this.http.post(`${this.STAKEHOLDERS_API}/auth/logout`, { refresh_token: refreshToken }, this.httpOptions)
+        .subscribe({ error: () => {} });
+    }
     localStorage.removeItem('currentUser');
+    localStorage.removeItem('accessToken');
+    localStorage.removeItem('refreshToken');
   }
 
   // Follow functionality
   followUser(userId: number, currentUserId: number = 1): Observable<FollowResponse> {
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      'X-User-ID': currentUserId.toString()
+      'Content-Type': 'application/json'
     });
     return this.http.post<FollowResponse>(`${this.STAKEHOLDERS_API}/follow/${userId}`, {}, { headers });
   }
 
   unfollowUser(userId: number, currentUserId: number = 1): Observable<FollowResponse> {
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.delete<FollowResponse>(`${this.STAKEHOLDERS_API}/follow/${userId}`, { headers });
+    return this.http.delete<FollowResponse>(`${this.STAKEHOLDERS_API}/follow/${userId}`);
   }
 
   checkFollowing(userId: number, currentUserId: number = 1): Observable<FollowCheckResponse> {
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.get<FollowCheckResponse>(`${this.STAKEHOLDERS_API}/follow/check/${userId}`, { headers });
+    return this.http.get<FollowCheckResponse>(`${this.STAKEHOLDERS_API}/follow/check/${userId}`);
   }
 
   getFollowing(currentUserId: number = 1): Observable<FollowingResponse> {
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.get<FollowingResponse>(`${this.STAKEHOLDERS_API}/following`, { headers });
+    return this.http.get<FollowingResponse>(`${this.STAKEHOLDERS_API}/following`);
+  }
+
+  // IDs of everyone the caller follows, unlike /following not paginated
+  getFollowingIds(): Observable<{user_ids: number[], count: number}> {
+    return this.http.get<{user_ids: number[], count: number}>(`${this.STAKEHOLDERS_API}/following/ids`);
   }
 
   getFollowers(currentUserId: number = 1): Observable<FollowersResponse> {
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.get<FollowersResponse>(`${this.STAKEHOLDERS_API}/followers`, { headers });
+    return this.http.get<FollowersResponse>(`${this.STAKEHOLDERS_API}/followers`);
   }
 
   canComment(authorId: number, currentUserId: number = 1): Observable<CanCommentResponse> {
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.get<CanCommentResponse>(`${this.STAKEHOLDERS_API}/can-comment/${authorId}`, { headers });
+    return this.http.get<CanCommentResponse>(`${this.STAKEHOLDERS_API}/can-comment/${authorId}`);
   }
 
   // Enhanced blog functionality with follow checking
   getRealBlogs(currentUserId: number = 1): Observable<{blogs: Blog[], pagination: any}> {
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.get<{blogs: Blog[], pagination: any}>(`${this.CONTENT_API}/blogs`, { headers });
+    return this.http.get<{blogs: Blog[], pagination: any}>(`${this.CONTENT_API}/blogs`);
   }
 
   addCommentWithFollowCheck(blogId: string, text: string, currentUserId: number = 1): Observable<any> {
     const headers = new HttpHeaders({ 
-      'Content-Type': 'application/json',
-      'X-User-ID': currentUserId.toString() 
+      'Content-Type': 'application/json'
     });
     return this.http.post(`${this.CONTENT_API}/blogs/${blogId}/comments`, { text }, { headers });
   }
@@ -710,8 +678,7 @@ removeKeypoint(tourId: string, order: number, userId?: number): Observable<any>
   updatePosition(positionData: PositionData): Observable<any> {
     const currentUserId = this.getCurrentUserId();
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      'X-User-ID': currentUserId.toString()
+      'Content-Type': 'application/json'
     });
     return this.http.post<any>(`${this.CONTENT_API}/positions/${currentUserId}`, positionData, { headers });
   }
@@ -755,10 +722,8 @@ removeKeypoint(tourId: string, order: number, userId?: number): Observable<any>
 
     // Tour Execution API calls
   startTour(tourId: string): Observable<{message: string, tour_execution: TourExecution}> {
-    const currentUserId = this.getCurrentUserId();
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      'X-User-ID': currentUserId.toString()
+      'Content-Type': 'application/json'
     });
     
     const request: StartTourRequest = { tour_id: tourId };
@@ -770,10 +735,8 @@ removeKeypoint(tourId: string, order: number, userId?: number): Observable<any>
   }
 
   checkKeypoints(): Observable<CheckKeypointsResponse> {
-    const currentUserId = this.getCurrentUserId();
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      'X-User-ID': currentUserId.toString()
+      'Content-Type': 'application/json'
     });
     
     return this.http.post<CheckKeypointsResponse>(
@@ -784,70 +747,44 @@ removeKeypoint(tourId: string, order: number, userId?: number): Observable<any>
   }
 
   abandonTour(executionId: string): Observable<{message: string}> {
-    const currentUserId = this.getCurrentUserId();
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    
     return this.http.put<{message: string}>(
       `${this.CONTENT_API}/executions/${executionId}/abandon`, 
-      {}, 
-      { headers }
+      {}
     );
   }
 
   getUserExecutions(userId?: number): Observable<{executions: TourExecution[]}> {
-    const currentUserId = userId || this.getCurrentUserId();
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.get<{executions: TourExecution[]}>(`${this.CONTENT_API}/tours/executions`, { headers });
+    return this.http.get<{executions: TourExecution[]}>(`${this.CONTENT_API}/tours/executions`);
   }
 
   // Dodajte ove metode u src/app/services/api.service.ts:
 
 // Keypoint Management API calls
 updateKeypoint(tourId: string, keypointOrder: number, data: AddKeypointRequest, userId?: number): Observable<any> {
-    const currentUserId = userId || this.getCurrentUserId();
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      'X-User-ID': currentUserId.toString()
+      'Content-Type': 'application/json'
     });
     return this.http.put(`${this.CONTENT_API}/tours/${tourId}/keypoints/${keypointOrder}`, data, { headers });
   }
 
 // Clear all keypoints (bonus method)
 clearAllKeypoints(tourId: string, userId?: number): Observable<any> {
-    const currentUserId = userId || this.getCurrentUserId();
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.delete(`${this.CONTENT_API}/tours/${tourId}/keypoints`, { headers });
+    return this.http.delete(`${this.CONTENT_API}/tours/${tourId}/keypoints`);
   }
 
 addTransportTime(tourId: string, transportTime: TransportTime, userId?: number): Observable<any> {
-    const currentUserId = userId || this.getCurrentUserId();
     const headers = new HttpHeaders({
-      'Content-Type': 'application/json',
-      'X-User-ID': currentUserId.toString()
+      'Content-Type': 'application/json'
     });
     return this.http.post(`${this.CONTENT_API}/tours/${tourId}/transport-times`, transportTime, { headers });
   }
 
 removeTransportTime(tourId: string, transportType: string, userId?: number): Observable<any> {
-    const currentUserId = userId || this.getCurrentUserId();
-    const headers = new HttpHeaders({
-      'X-User-ID': currentUserId.toString()
-    });
-    return this.http.delete(`${this.CONTENT_API}/tours/${tourId}/transport-times/${transportType}`, { headers });
+    return this.http.delete(`${this.CONTENT_API}/tours/${tourId}/transport-times/${transportType}`);
   }
 
 getCart(userId?: number): Observable<{cart: ShoppingCart, message: string}> {
-  const currentUserId = userId || this.getCurrentUserId();
-  const headers = new HttpHeaders({
-    'X-User-ID': currentUserId.toString()
-  });
-  return this.http.get<{cart: ShoppingCart, message: string}>(`${this.COMMERCE_API}/cart`, { headers })
+  return this.http.get<{cart: ShoppingCart, message: string}>(`${this.COMMERCE_API}/cart`)
     .pipe(
       map(response => ({
         ...response,
@@ -860,45 +797,29 @@ getCart(userId?: number): Observable<{cart: ShoppingCart, message: string}> {
 }
 
 addToCart(tourData: AddToCartRequest, userId?: number): Observable<{message: string}> {
-  const currentUserId = userId || this.getCurrentUserId();
   const headers = new HttpHeaders({
-    'Content-Type': 'application/json',
-    'X-User-ID': currentUserId.toString()
+    'Content-Type': 'application/json'
   });
   return this.http.post<{message: string}>(`${this.COMMERCE_API}/cart/add`, tourData, { headers });
 }
 
 removeFromCart(tourId: string, userId?: number): Observable<{message: string}> {
-  const currentUserId = userId || this.getCurrentUserId();
-  const headers = new HttpHeaders({
-    'X-User-ID': currentUserId.toString()
-  });
-  return this.http.delete<{message: string}>(`${this.COMMERCE_API}/cart/remove/${tourId}`, { headers });
+  return this.http.delete<{message: string}>(`${this.COMMERCE_API}/cart/remove/${tourId}`);
 }
 
 checkout(userId?: number): Observable<CheckoutResponse> {
-  const currentUserId = userId || this.getCurrentUserId();
   const headers = new HttpHeaders({
-    'Content-Type': 'application/json',
-    'X-User-ID': currentUserId.toString()
+    'Content-Type': 'application/json'
   });
   return this.http.post<CheckoutResponse>(`${this.COMMERCE_API}/checkout`, {}, { headers });
 }
 
 getPurchases(userId?: number): Observable<{purchases: PurchaseToken[], count: number}> {
-  const currentUserId = userId || this.getCurrentUserId();
-  const headers = new HttpHeaders({
-    'X-User-ID': currentUserId.toString()
-  });
-  return this.http.get<{purchases: PurchaseToken[], count: number}>(`${this.COMMERCE_API}/purchases`, { headers });
+  return this.http.get<{purchases: PurchaseToken[], count: number}>(`${this.COMMERCE_API}/purchases`);
 }
 
 checkTourPurchase(tourId: string, userId?: number): Observable<TourPurchaseInfo> {
-  const currentUserId = userId || this.getCurrentUserId();
-  const headers = new HttpHeaders({
-    'X-User-ID': currentUserId.toString()
-  });
-  return this.http.get<TourPurchaseInfo>(`${this.COMMERCE_API}/purchase/check/${tourId}`, { headers });
+  return this.http.get<TourPurchaseInfo>(`${this.COMMERCE_API}/purchase/check/${tourId}`);
 }
 
 
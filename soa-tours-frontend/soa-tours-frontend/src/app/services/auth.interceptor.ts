@@ -0,0 +1,79 @@
+import {
+  HttpBackend,
+  HttpClient,
+  HttpErrorResponse,
+  HttpInterceptorFn,
+  HttpRequest
+} from '@angular/common/http';
+import { inject } from '@angular/core';
+import { Observable, catchError, finalize, map, shareReplay, switchMap, throwError } from 'rxjs';
+
+const REFRESH_URL = 'http://localhost:8080/stakeholders/auth/refresh';
+
+interface RefreshResponse {
+  access_token: string;
+  refresh_token: string;
+}
+
+// Requests failing while a refresh runs wait for the same one, the refresh
+// token rotates and may only be used once
+let refreshInFlight: Observable<string> | null = null;
+
+function withToken(req: HttpRequest<unknown>, token: string): HttpRequest<unknown> {
+  return req.clone({ setHeaders: { Authorization: `Bearer ${token}` } });
+}
+
+function endSession(): void {
+  localStorage.removeItem('currentUser');
+  localStorage.removeItem('accessToken');
+  localStorage.removeItem('refreshToken');
+}
+
+function refreshAccessToken(http: HttpClient): Observable<string> {
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) {
+    return throwError(() => new Error('No refresh token'));
+  }
+
+  if (!refreshInFlight) {
+    refreshInFlight = http.post<RefreshResponse>(REFRESH_URL, { refresh_token: refreshToken }).pipe(
+      map(tokens => {
+        localStorage.setItem('accessToken', tokens.access_token);
+        localStorage.setItem('refreshToken', tokens.refresh_token);
+        return tokens.access_token;
+      }),
+      catchError(error => {
+        // The session was revoked or expired, the user has to log in again
+        endSession();
+        return throwError(() => error);
+      }),
+      finalize(() => refreshInFlight = null),
+      shareReplay(1)
+    );
+  }
+  return refreshInFlight;
+}
+
+// Attach the access token from login to every backend request. An expired
+// access token is renewed with the refresh token and the request retried once.
+export const authInterceptor: HttpInterceptorFn = (req, next) => {
+  const token = localStorage.getItem('accessToken');
+  if (!token || req.headers.has('Authorization')) {
+    return next(req);
+  }
+
+  // HttpBackend skips the interceptors, so the refresh call cannot loop back here
+  const refreshClient = new HttpClient(inject(HttpBackend));
+
+  return next(withToken(req, token)).pipe(
+    catchError((error: unknown) => {
+      if (!(error instanceof HttpErrorResponse) || error.status !== 401 || req.url.includes('/auth/')) {
+        return throwError(() => error);
+      }
+      return refreshAccessToken(refreshClient).pipe(
+        catchError(() => throwError(() => error)),
+        switchMap(newToken => next(withToken(req, newToken)))
+      );
+    })
+  );
+};
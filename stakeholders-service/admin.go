@@ -0,0 +1,369 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"stakeholders-service/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit actions written to admin_audit_log
+const (
+	auditBlockUser      = "block_user"
+	auditUnblockUser    = "unblock_user"
+	auditChangeRole     = "change_role"
+	auditDeleteUser     = "delete_user"
+	auditRevokeSessions = "revoke_sessions"
+	auditUnlockLogin    = "unlock_login"
+)
+
+type AuditEntry struct {
+	ID           int             `json:"id" db:"id"`
+	AdminID      int             `json:"admin_id" db:"admin_id"`
+	TargetUserID int             `json:"target_user_id" db:"target_user_id"`
+	Action       string          `json:"action" db:"action"`
+	Reason       string          `json:"reason" db:"reason"`
+	Details      json.RawMessage `json:"details,omitempty" db:"details"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+}
+
+type BlockUserRequest struct {
+	Reason string `json:"reason" binding:"required,min=3,max=255"`
+}
+
+type UnblockUserRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type ChangeRoleRequest struct {
+	Role   string `json:"role" binding:"required,oneof=admin guide tourist"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// recordAudit writes one admin action, details is optional extra context
+func recordAudit(exec execer, adminID, targetUserID int, action, reason string, details gin.H) error {
+	var detailsJSON interface{}
+	if details != nil {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		detailsJSON = string(encoded)
+	}
+
+	_, err := exec.Exec(
+		"INSERT INTO admin_audit_log (admin_id, target_user_id, action, reason, details) VALUES (?, ?, ?, ?, ?)",
+		adminID, targetUserID, action, reason, detailsJSON,
+	)
+	return err
+}
+
+// adminTarget parses :id and loads the target user, writing the error response
+// when the id is invalid, the user does not exist or is the calling admin
+func adminTarget(c *gin.Context) (*User, bool) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	if adminID, _ := auth.UserID(c); adminID == targetID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot change their own account"})
+		return nil, false
+	}
+
+	var user User
+	err = db.QueryRow(
+		"SELECT id, username, email, role, is_blocked FROM users WHERE id = ? AND deleted_at IS NULL",
+		targetID,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.IsBlocked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return nil, false
+		}
+		log.Printf("Error querying user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// setBlocked blocks or unblocks a user, blocking also ends every session
+func setBlocked(c *gin.Context, blocked bool, reason string) {
+	target, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	adminID, _ := auth.UserID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("UPDATE users SET is_blocked = ? WHERE id = ?", blocked, target.ID); err != nil {
+		log.Printf("Error updating user block state: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	action := auditUnblockUser
+	var revoked int64
+	if blocked {
+		action = auditBlockUser
+		if revoked, err = revokeUserSessions(tx, target.ID); err != nil {
+			log.Printf("Error revoking sessions: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+	}
+
+	if err = recordAudit(tx, adminID, target.ID, action, reason, nil); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write audit log"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "User block state updated successfully",
+		"user_id":          target.ID,
+		"is_blocked":       blocked,
+		"revoked_sessions": revoked,
+	})
+}
+
+// POST /admin/users/:id/block
+func blockUser(c *gin.Context) {
+	var req BlockUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	setBlocked(c, true, req.Reason)
+}
+
+// POST /admin/users/:id/unblock
+func unblockUser(c *gin.Context) {
+	var req UnblockUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	setBlocked(c, false, req.Reason)
+}
+
+// PUT /admin/users/:id/role - the role lives in access tokens, so the user's
+// sessions are revoked and the new role applies from the next login
+func changeUserRole(c *gin.Context) {
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	adminID, _ := auth.UserID(c)
+
+	if target.Role == req.Role {
+		c.JSON(http.StatusOK, gin.H{"message": "User already has this role", "user_id": target.ID, "role": req.Role})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("UPDATE users SET role = ? WHERE id = ?", req.Role, target.ID); err != nil {
+		log.Printf("Error updating user role: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	if _, err = revokeUserSessions(tx, target.ID); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	details := gin.H{"old_role": target.Role, "new_role": req.Role}
+	if err = recordAudit(tx, adminID, target.ID, auditChangeRole, req.Reason, details); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write audit log"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "User role updated successfully",
+		"user_id":  target.ID,
+		"old_role": target.Role,
+		"role":     req.Role,
+	})
+}
+
+// DELETE /admin/users/:id?reason= - soft delete, the row stays for audit and
+// purchase history, follows and shopping carts are removed
+func deleteUser(c *gin.Context) {
+	reason := c.Query("reason")
+	if len(reason) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason must be at most 255 characters"})
+		return
+	}
+
+	target, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	adminID, _ := auth.UserID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("UPDATE users SET deleted_at = NOW() WHERE id = ?", target.ID); err != nil {
+		log.Printf("Error deleting user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	if _, err = revokeUserSessions(tx, target.ID); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	follows, err := tx.Exec("DELETE FROM follows WHERE follower_id = ? OR following_id = ?", target.ID, target.ID)
+	if err != nil {
+		log.Printf("Error deleting follows: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete follows"})
+		return
+	}
+
+	// cart_items are removed by ON DELETE CASCADE
+	carts, err := tx.Exec("DELETE FROM shopping_carts WHERE user_id = ?", target.ID)
+	if err != nil {
+		log.Printf("Error deleting shopping cart: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shopping cart"})
+		return
+	}
+
+	removedFollows, _ := follows.RowsAffected()
+	removedCarts, _ := carts.RowsAffected()
+	details := gin.H{"username": target.Username, "removed_follows": removedFollows, "removed_carts": removedCarts}
+	if err = recordAudit(tx, adminID, target.ID, auditDeleteUser, reason, details); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write audit log"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "User deleted successfully",
+		"user_id":         target.ID,
+		"removed_follows": removedFollows,
+		"removed_carts":   removedCarts,
+	})
+}
+
+// GET /admin/audit - optional filters: target_user_id, admin_id, action
+func getAuditLog(c *gin.Context) {
+	page := parsePagination(c, 20, 100)
+
+	where := "WHERE 1 = 1"
+	var args []interface{}
+	for _, filter := range []string{"target_user_id", "admin_id"} {
+		if value := c.Query(filter); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + filter})
+				return
+			}
+			where += " AND " + filter + " = ?"
+			args = append(args, id)
+		}
+	}
+	if action := c.Query("action"); action != "" {
+		where += " AND action = ?"
+		args = append(args, action)
+	}
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM admin_audit_log "+where, args...).Scan(&total); err != nil {
+		log.Printf("Error counting audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	rows, err := db.Query(
+		"SELECT id, admin_id, target_user_id, action, reason, details, created_at FROM admin_audit_log "+
+			where+" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",
+		append(args, page.Limit, page.Offset())...,
+	)
+	if err != nil {
+		log.Printf("Error querying audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var reason sql.NullString
+		var details []byte
+		if err := rows.Scan(&entry.ID, &entry.AdminID, &entry.TargetUserID, &entry.Action, &reason, &details, &entry.CreatedAt); err != nil {
+			log.Printf("Error scanning audit entry: %v", err)
+			continue
+		}
+		entry.Reason = reason.String
+		if len(details) > 0 {
+			entry.Details = details
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"pagination": page.Response(total),
+	})
+}
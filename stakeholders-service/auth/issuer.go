@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenIssuer signs access tokens, only stakeholders-service issues them
+type TokenIssuer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokenIssuer(secret string, ttl time.Duration) *TokenIssuer {
+	return &TokenIssuer{secret: []byte(secret), ttl: ttl}
+}
+
+// Issue returns a signed HS256 access token for the identity claims (user,
+// role, email status and refresh token session) and its expiry time
+func (i *TokenIssuer) Issue(identity Claims) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+
+	claims := identity
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    Issuer,
+		Subject:   strconv.Itoa(identity.UserID),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
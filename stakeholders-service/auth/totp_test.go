@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// Base32 of the RFC 6238 SHA1 test key "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 appendix B lists 8 digit codes, 6 digit codes are their last 6 digits
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, tt := range rfcVectors {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      time.Time
+		lastStep int64
+		want     bool
+	}{
+		{"current step", rfcSecret, "050471", now, 0, true},
+		{"lower case secret and spaces", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "050 471", now, 0, true},
+		{"previous step within skew", rfcSecret, "050471", now.Add(totpPeriod * time.Second), 0, true},
+		{"next step within skew", rfcSecret, "050471", now.Add(-totpPeriod * time.Second), 0, true},
+		{"outside skew", rfcSecret, "050471", now.Add(2 * totpPeriod * time.Second), 0, false},
+		{"replayed step", rfcSecret, "050471", now, step, false},
+		{"wrong code", rfcSecret, "050472", now, 0, false},
+		{"wrong length", rfcSecret, "14050471", now, 0, false},
+		{"invalid secret", "not base32!", "050471", now, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(tt.secret, tt.code, tt.now, tt.lastStep)
+			if ok != tt.want {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.want)
+			}
+			if ok && got != step {
+				t.Errorf("ValidateTOTP step = %d, want %d", got, step)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func signedToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token
+}
+
+func validClaims() Claims {
+	return Claims{
+		UserID:   7,
+		Username: "ana",
+		Role:     "tourist",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		valid bool
+	}{
+		{"valid", func(t *testing.T) string {
+			return signedToken(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims())
+		}, true},
+		{"wrong secret", func(t *testing.T) string {
+			return signedToken(t, jwt.SigningMethodHS256, []byte("other-secret"), validClaims())
+		}, false},
+		{"HS512 instead of HS256", func(t *testing.T) string {
+			return signedToken(t, jwt.SigningMethodHS512, []byte(testSecret), validClaims())
+		}, false},
+		{"alg none", func(t *testing.T) string {
+			return signedToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims())
+		}, false},
+		{"wrong issuer", func(t *testing.T) string {
+			claims := validClaims()
+			claims.Issuer = "someone-else"
+			return signedToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+		}, false},
+		{"expired", func(t *testing.T) string {
+			claims := validClaims()
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			return signedToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+		}, false},
+		{"no expiry", func(t *testing.T) string {
+			claims := validClaims()
+			claims.ExpiresAt = nil
+			return signedToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+		}, false},
+		{"no user id", func(t *testing.T) string {
+			claims := validClaims()
+			claims.UserID = 0
+			return signedToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+		}, false},
+		{"malformed", func(t *testing.T) string { return "not.a.token" }, false},
+	}
+
+	verifier := NewVerifier(testSecret)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token(t))
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("Verify error = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.UserID != 7 || claims.Username != "ana" {
+				t.Errorf("Verify claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifySessionCheck(t *testing.T) {
+	token := signedToken(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims())
+
+	active := NewVerifier(testSecret).WithSessionCheck(func(*Claims) (bool, error) { return true, nil })
+	if _, err := active.Verify(token); err != nil {
+		t.Errorf("active session: %v", err)
+	}
+
+	revoked := NewVerifier(testSecret).WithSessionCheck(func(*Claims) (bool, error) { return false, nil })
+	if _, err := revoked.Verify(token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("revoked session error = %v, want ErrSessionRevoked", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"Bearer abc", "abc", true},
+		{"bearer  abc ", "abc", true},
+		{"Basic abc", "", false},
+		{"Bearer", "", false},
+		{"Bearer ", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := BearerToken(tt.header)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("BearerToken(%q) = %q, %v", tt.header, got, err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ require (
     github.com/gin-contrib/cors v1.4.0
     github.com/go-sql-driver/mysql v1.7.1
     golang.org/x/crypto v0.17.0
+    github.com/golang-jwt/jwt/v5 v5.2.1
 )
 
 require (
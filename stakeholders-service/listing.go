@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination, search and sorting shared by the user and profile listings
+
+type pagination struct {
+	Page  int
+	Limit int
+}
+
+func (p pagination) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+func (p pagination) Response(total int64) gin.H {
+	return gin.H{
+		"page":        p.Page,
+		"limit":       p.Limit,
+		"total":       total,
+		"total_pages": (total + int64(p.Limit) - 1) / int64(p.Limit),
+	}
+}
+
+// parsePagination reads ?page= and ?limit=, invalid values fall back to defaults
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) pagination {
+	p := pagination{Page: 1, Limit: defaultLimit}
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			p.Page = page
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= maxLimit {
+			p.Limit = limit
+		}
+	}
+
+	return p
+}
+
+// sortClause builds ORDER BY from ?sort= and ?order=. Only keys of allowed are
+// accepted and mapped to their comma separated columns, so user input never
+// reaches SQL. The id tie-breaker keeps page boundaries stable.
+func sortClause(c *gin.Context, allowed map[string]string, defaultSort, idColumn string) (string, bool) {
+	columns, ok := allowed[c.DefaultQuery("sort", defaultSort)]
+	if !ok {
+		return "", false
+	}
+
+	order := strings.ToUpper(c.DefaultQuery("order", "desc"))
+	if order != "ASC" && order != "DESC" {
+		return "", false
+	}
+
+	var terms []string
+	for _, column := range strings.Split(columns+","+idColumn, ",") {
+		terms = append(terms, strings.TrimSpace(column)+" "+order)
+	}
+	return " ORDER BY " + strings.Join(terms, ", "), true
+}
+
+// escapeLike escapes LIKE wildcards so search text is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// nameSearch matches the username or first/last name by prefix so the
+// idx_username and idx_full_name (first_name, last_name) indexes apply.
+// "Jane Tou" matches first_name = Jane and last_name starting with Tou.
+func nameSearch(q, userAlias, profileAlias string) (string, []interface{}) {
+	q = strings.TrimSpace(q)
+	prefix := escapeLike(q) + "%"
+
+	clause := " AND (" + userAlias + ".username LIKE ? OR " +
+		profileAlias + ".first_name LIKE ? OR " +
+		profileAlias + ".last_name LIKE ?"
+	args := []interface{}{prefix, prefix, prefix}
+
+	if first, last, found := strings.Cut(q, " "); found {
+		clause += " OR (" + profileAlias + ".first_name = ? AND " + profileAlias + ".last_name LIKE ?)"
+		args = append(args, first, escapeLike(strings.TrimSpace(last))+"%")
+	}
+
+	return clause + ")", args
+}
+
+// userFilters reads ?role=, ?blocked= and ?q= into a WHERE fragment, the
+// profile table must be joined as p. Invalid values return an error message.
+// ?blocked= is only accepted on admin listings, public ones must not reveal
+// which accounts are blocked.
+func userFilters(c *gin.Context, userAlias string, adminListing bool) (string, []interface{}, string) {
+	var clause string
+	var args []interface{}
+
+	if role := c.Query("role"); role != "" {
+		if role != "admin" && role != "guide" && role != "tourist" {
+			return "", nil, "Invalid role filter"
+		}
+		clause += " AND " + userAlias + ".role = ?"
+		args = append(args, role)
+	}
+
+	if blockedStr := c.Query("blocked"); blockedStr != "" {
+		if !adminListing {
+			return "", nil, "The blocked filter is only available to admins"
+		}
+		blocked, err := strconv.ParseBool(blockedStr)
+		if err != nil {
+			return "", nil, "Invalid blocked filter"
+		}
+		clause += " AND " + userAlias + ".is_blocked = ?"
+		args = append(args, blocked)
+	}
+
+	if q := c.Query("q"); strings.TrimSpace(q) != "" {
+		searchClause, searchArgs := nameSearch(q, userAlias, "p")
+		clause += searchClause
+		args = append(args, searchArgs...)
+	}
+
+	return clause, args, ""
+}
@@ -0,0 +1,41 @@
+// Package mail sends account emails (verification, password reset) through
+// a pluggable Mailer so local development needs no SMTP server.
+package mail
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FromEnv picks the mailer from MAIL_DRIVER: "smtp" uses SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM, anything else writes mails to
+// MAIL_LOG_FILE or the service log
+func FromEnv() Mailer {
+	if os.Getenv("MAIL_DRIVER") != "smtp" {
+		return NewLogMailer(os.Getenv("MAIL_LOG_FILE"))
+	}
+
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil {
+		port = 587
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@soatours.com"
+	}
+
+	log.Printf("Sending mail through SMTP %s:%d", os.Getenv("SMTP_HOST"), port)
+	return NewSMTPMailer(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+}
@@ -4,10 +4,15 @@ import (
     "database/sql"
     "log"
     "net/http"
+    "net/url"
     "os"
     "strconv"
+    "strings"
     "time"
 
+    "stakeholders-service/auth"
+    "stakeholders-service/mail"
+
     "github.com/gin-gonic/gin"
     "github.com/gin-contrib/cors"
     _ "github.com/go-sql-driver/mysql"
@@ -21,6 +26,7 @@ type User struct {
     PasswordHash string `json:"-" db:"password_hash"`
     Role         string `json:"role" db:"role"`
     IsBlocked    bool   `json:"is_blocked" db:"is_blocked"`
+    EmailVerified bool  `json:"email_verified" db:"email_verified"`
     CreatedAt    time.Time `json:"created_at" db:"created_at"`
     UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -54,16 +60,28 @@ type RegisterRequest struct {
 }
 
 type LoginRequest struct {
-    Username string `json:"username" binding:"required"`
+    Username string `json:"username" binding:"required,max=100"`
     Password string `json:"password" binding:"required"`
 }
 
+// Limits follow the profiles columns, biography is TEXT (64 KB) and counted in
+// characters, so 10000 stays below it even with 4-byte UTF-8
 type UpdateProfileRequest struct {
-    FirstName    string `json:"first_name"`
-    LastName     string `json:"last_name"`
-    ProfileImage string `json:"profile_image"`
-    Biography    string `json:"biography"`
-    Motto        string `json:"motto"`
+    FirstName    string `json:"first_name" binding:"max=50"`
+    LastName     string `json:"last_name" binding:"max=50"`
+    ProfileImage string `json:"profile_image" binding:"max=500"`
+    Biography    string `json:"biography" binding:"max=10000"`
+    Motto        string `json:"motto" binding:"max=255"`
+}
+
+// PatchProfileRequest changes only the fields present, null or missing
+// leaves a field as is and "" clears it
+type PatchProfileRequest struct {
+    FirstName    *string `json:"first_name" binding:"omitempty,max=50"`
+    LastName     *string `json:"last_name" binding:"omitempty,max=50"`
+    ProfileImage *string `json:"profile_image" binding:"omitempty,max=500"`
+    Biography    *string `json:"biography" binding:"omitempty,max=10000"`
+    Motto        *string `json:"motto" binding:"omitempty,max=255"`
 }
 
 // NOVE STRUKTURE ZA FOLLOW FUNKCIONALNOST
@@ -81,27 +99,42 @@ type FollowWithUser struct {
     LastName  string `json:"last_name"`
 }
 
-var db *sql.DB
+type CommentPermissionPair struct {
+    UserID   int `json:"user_id" binding:"required,min=1"`
+    AuthorID int `json:"author_id" binding:"required,min=1"`
+}
 
-// Mock auth middleware - za testiranje
-func authMiddleware() gin.HandlerFunc {
-    return func(c *gin.Context) {
-        // Za testiranje, koristimo mock user_id
-        // U realnoj aplikaciji ovde bi se parsirao JWT token
-        userID := c.GetHeader("X-User-ID")
-        if userID == "" {
-            userID = "1" // default mock user
-        }
-        
-        if id, err := strconv.Atoi(userID); err == nil {
-            c.Set("user_id", id)
-        } else {
-            c.Set("user_id", 1)
-        }
-        c.Next()
-    }
+type CanCommentBatchRequest struct {
+    Pairs []CommentPermissionPair `json:"pairs" binding:"required,min=1,max=500,dive"`
 }
 
+type CommentPermission struct {
+    UserID     int    `json:"user_id"`
+    AuthorID   int    `json:"author_id"`
+    CanComment bool   `json:"can_comment"`
+    Reason     string `json:"reason"`
+}
+
+type UserLookupRequest struct {
+    Usernames []string `json:"usernames" binding:"required,min=1,max=50,dive,min=1,max=50"`
+}
+
+type UserRef struct {
+    ID       int    `json:"id"`
+    Username string `json:"username"`
+}
+
+var db *sql.DB
+
+// Access tokens are signed with JWT_SECRET and verified by every service.
+// They are short lived, clients renew them with the refresh token (session.go).
+const accessTokenTTL = 15 * time.Minute
+
+var (
+    tokenIssuer   *auth.TokenIssuer
+    tokenVerifier *auth.Verifier
+)
+
 func main() {
     var err error
     
@@ -123,13 +156,26 @@ func main() {
     }
     log.Println("Connected to MySQL database")
 
+    jwtSecret := auth.SecretFromEnv()
+    tokenIssuer = auth.NewTokenIssuer(jwtSecret, accessTokenTTL)
+    tokenVerifier = auth.NewVerifier(jwtSecret).WithSessionCheck(isSessionActive)
+    mailer = mail.FromEnv()
+    requireAuth := auth.Middleware(tokenVerifier)
+    optionalAuth := auth.OptionalMiddleware(tokenVerifier)
+
     router := gin.Default()
 
+    // Only the gateway may set X-Forwarded-For. Trusting every sender would let
+    // clients pick the IP the login and password reset throttles count against.
+    if err := router.SetTrustedProxies(trustedProxiesFromEnv()); err != nil {
+        log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+    }
+
     // CORS configuration
     config := cors.DefaultConfig()
     config.AllowOrigins = []string{"http://localhost:4200", "http://127.0.0.1:4200"}
-    config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-    config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-User-ID"}
+    config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+    config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
     router.Use(cors.New(config))
 
     // Health check
@@ -138,23 +184,63 @@ func main() {
     // Authentication routes
     router.POST("/auth/register", registerUser)
     router.POST("/auth/login", loginUser)
+    router.POST("/auth/refresh", refreshSession)
+    router.POST("/auth/logout", logoutUser)
+    router.POST("/auth/login/2fa", completeLoginChallenge)
+    router.POST("/auth/verify-email/request", requireAuth, requestEmailVerification)
+    router.POST("/auth/verify-email", verifyEmail)
+    router.POST("/auth/password-reset/request", requestPasswordReset)
+    router.POST("/auth/password-reset", resetPassword)
+
+    // Two-factor authentication, optional for guides and admins
+    twoFactorRoles := auth.RequireRole(auth.RoleGuide, auth.RoleAdmin)
+    router.POST("/auth/2fa/setup", requireAuth, twoFactorRoles, setupTwoFactor)
+    router.POST("/auth/2fa/confirm", requireAuth, twoFactorRoles, confirmTwoFactor)
+    router.POST("/auth/2fa/recovery-codes", requireAuth, regenerateRecoveryCodes)
+    router.POST("/auth/2fa/disable", requireAuth, disableTwoFactor)
 
     // User routes
-    router.GET("/users", getUsers)
+    router.GET("/users", requireAuth, auth.RequireRole(auth.RoleAdmin), getUsers)
     router.GET("/users/:id", getUserByID)
     router.GET("/users/:id/profile", getUserProfile)
-    router.PUT("/users/:id/profile", updateUserProfile)
+    router.PUT("/users/:id/profile", requireAuth, auth.RequireOwnerOrAdmin("id"), updateUserProfile)
+    router.PATCH("/users/:id/profile", requireAuth, auth.RequireOwnerOrAdmin("id"), patchUserProfile)
+    router.POST("/users/:id/sessions/revoke", requireAuth, auth.RequireRole(auth.RoleAdmin), revokeSessions)
+    router.GET("/internal/sessions/:id", getSessionStatus)
+
+    // Admin user management - every action is written to admin_audit_log
+    admin := router.Group("/admin", requireAuth, auth.RequireRole(auth.RoleAdmin))
+    admin.POST("/users/:id/block", blockUser)
+    admin.POST("/users/:id/unblock", unblockUser)
+    admin.PUT("/users/:id/role", changeUserRole)
+    admin.DELETE("/users/:id", deleteUser)
+    admin.GET("/audit", getAuditLog)
+    admin.GET("/lockouts", getLoginLockouts)
+    admin.POST("/users/:id/unlock-login", unlockLogin)
 
     // Profile routes
     router.GET("/profiles", getProfiles)
 
     // NOVE FOLLOW RUTE
-    router.POST("/follow/:user_id", authMiddleware(), followUser)
-    router.DELETE("/follow/:user_id", authMiddleware(), unfollowUser)
-    router.GET("/follow/check/:user_id", authMiddleware(), checkFollowing)
-    router.GET("/following", authMiddleware(), getFollowing)
-    router.GET("/followers", authMiddleware(), getFollowers)
-    router.GET("/can-comment/:author_id", authMiddleware(), canComment)
+    router.POST("/follow/:user_id", requireAuth, followUser)
+    router.DELETE("/follow/:user_id", requireAuth, unfollowUser)
+    router.GET("/follow/check/:user_id", requireAuth, checkFollowing)
+    router.GET("/following", requireAuth, getFollowing)
+    router.GET("/following/ids", requireAuth, getFollowingIDs)
+    router.GET("/followers", requireAuth, getFollowers)
+    router.GET("/can-comment/:author_id", requireAuth, canComment)
+    router.POST("/can-comment/batch", requireAuth, canCommentBatch)
+    router.POST("/users/lookup", requireAuth, lookupUsers)
+    router.GET("/recommendations/follow", requireAuth, recommendFollows)
+
+    // Personal data export and account deletion, across all services
+    router.GET("/me/export", requireAuth, exportMyData)
+    router.DELETE("/me", requireAuth, deleteMyAccount)
+
+    // Follow lists and stats for any user
+    router.GET("/users/:id/followers", getUserFollowers)
+    router.GET("/users/:id/following", getUserFollowing)
+    router.GET("/users/:id/follow-stats", optionalAuth, getFollowStats)
 
     port := os.Getenv("PORT")
     if port == "" {
@@ -165,6 +251,18 @@ func main() {
     log.Fatal(router.Run("0.0.0.0:" + port))
 }
 
+// trustedProxiesFromEnv reads the comma separated TRUSTED_PROXIES IPs or CIDRs,
+// without it no proxy is trusted and the connection's address is the client IP
+func trustedProxiesFromEnv() []string {
+    var proxies []string
+    for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+        if proxy = strings.TrimSpace(proxy); proxy != "" {
+            proxies = append(proxies, proxy)
+        }
+    }
+    return proxies
+}
+
 func healthCheck(c *gin.Context) {
     c.JSON(http.StatusOK, gin.H{
         "status":  "healthy",
@@ -247,11 +345,20 @@ func registerUser(c *gin.Context) {
         return
     }
 
+    // A failed mail does not undo the registration, it can be requested again
+    verificationSent := true
+    if err := sendVerificationEmail(int(userID), req.Username, req.Email); err != nil {
+        log.Printf("Error sending verification email: %v", err)
+        verificationSent = false
+    }
+
     c.JSON(http.StatusCreated, gin.H{
         "message": "User registered successfully",
         "user_id": userID,
         "username": req.Username,
         "role": req.Role,
+        "email_verified": false,
+        "verification_email_sent": verificationSent,
     })
 }
 
@@ -262,14 +369,29 @@ func loginUser(c *gin.Context) {
         return
     }
 
+    retryAfter, err := loginRetryAfter(req.Username, c.ClientIP())
+    if err != nil {
+        log.Printf("Error checking login throttle: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+    if retryAfter > 0 {
+        tooManyAttempts(c, retryAfter)
+        return
+    }
+
     var user User
-    err := db.QueryRow(
-        "SELECT id, username, email, password_hash, role, is_blocked FROM users WHERE username = ?",
+    var twoFactorEnabled bool
+    err = db.QueryRow(
+        "SELECT id, username, email, password_hash, role, is_blocked, email_verified, totp_enabled FROM users WHERE username = ? AND deleted_at IS NULL",
         req.Username,
-    ).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.IsBlocked)
+    ).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.IsBlocked, &user.EmailVerified, &twoFactorEnabled)
     
     if err != nil {
         if err == sql.ErrNoRows {
+            // Same work and response as a wrong password
+            bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+            recordLoginFailure(req.Username, c.ClientIP())
             c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
             return
         }
@@ -278,38 +400,95 @@ func loginUser(c *gin.Context) {
         return
     }
 
+    // Check password
+    err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+    if err != nil {
+        recordLoginFailure(req.Username, c.ClientIP())
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+        return
+    }
+
+    // Only reported after the password matched, so it does not reveal the account
     if user.IsBlocked {
         c.JSON(http.StatusForbidden, gin.H{"error": "Account is blocked"})
         return
     }
 
-    // Check password
-    err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+    // Enrolled users finish the login with a code on /auth/login/2fa. Their
+    // failures are only cleared once the second factor is correct too.
+    if twoFactorEnabled {
+        startLoginChallenge(c, user)
+        return
+    }
+    clearLoginFailures(req.Username)
+
+    tokens, err := createSession(db, user)
     if err != nil {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+        log.Printf("Error creating session: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
         return
     }
 
-    // TODO: Generate JWT token here
-    c.JSON(http.StatusOK, gin.H{
-        "message": "Login successful",
+    c.JSON(http.StatusOK, loginResponse(user, tokens))
+}
+
+// loginResponse is the body of a completed login, with or without 2FA
+func loginResponse(user User, tokens *tokenPair) gin.H {
+    return gin.H{
+        "message":            "Login successful",
+        "access_token":       tokens.AccessToken,
+        "token_type":         tokens.TokenType,
+        "expires_at":         tokens.ExpiresAt,
+        "refresh_token":      tokens.RefreshToken,
+        "refresh_expires_at": tokens.RefreshExpiresAt,
         "user": gin.H{
             "id":       user.ID,
             "username": user.Username,
             "email":    user.Email,
             "role":     user.Role,
+            "email_verified": user.EmailVerified,
         },
-    })
+    }
 }
 
+// GET /users?page=&limit=&role=&blocked=&q=&sort=&order=
 func getUsers(c *gin.Context) {
-    rows, err := db.Query(`
-        SELECT u.id, u.username, u.email, u.role, u.is_blocked, u.created_at, u.updated_at,
-               p.first_name, p.last_name, p.profile_image, p.biography, p.motto
+    page := parsePagination(c, 20, 100)
+
+    filter, args, errMsg := userFilters(c, "u", true)
+    if errMsg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+        return
+    }
+
+    orderBy, ok := sortClause(c, map[string]string{
+        "created_at": "u.created_at",
+        "username":   "u.username",
+        "role":       "u.role",
+    }, "created_at", "u.id")
+    if !ok {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort parameters"})
+        return
+    }
+
+    from := `
         FROM users u
         LEFT JOIN profiles p ON u.id = p.user_id
-        ORDER BY u.created_at DESC
-    `)
+        WHERE u.deleted_at IS NULL` + filter
+
+    var total int64
+    if err := db.QueryRow("SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+        log.Printf("Error counting users: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+
+    rows, err := db.Query(`
+        SELECT u.id, u.username, u.email, u.role, u.is_blocked, u.email_verified, u.created_at, u.updated_at,
+               p.first_name, p.last_name, p.profile_image, p.biography, p.motto`+
+        from+orderBy+" LIMIT ? OFFSET ?",
+        append(args, page.Limit, page.Offset())...,
+    )
     if err != nil {
         log.Printf("Error querying users: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -324,7 +503,7 @@ func getUsers(c *gin.Context) {
         var firstName, lastName, profileImage, biography, motto sql.NullString
 
         err := rows.Scan(
-            &user.ID, &user.Username, &user.Email, &user.Role, &user.IsBlocked, &user.CreatedAt, &user.UpdatedAt,
+            &user.ID, &user.Username, &user.Email, &user.Role, &user.IsBlocked, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
             &firstName, &lastName, &profileImage, &biography, &motto,
         )
         if err != nil {
@@ -346,8 +525,9 @@ func getUsers(c *gin.Context) {
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "users": users,
-        "count": len(users),
+        "users":      users,
+        "count":      len(users),
+        "pagination": page.Response(total),
     })
 }
 
@@ -364,13 +544,13 @@ func getUserByID(c *gin.Context) {
     var firstName, lastName, profileImage, biography, motto sql.NullString
 
     err = db.QueryRow(`
-        SELECT u.id, u.username, u.email, u.role, u.is_blocked, u.created_at, u.updated_at,
+        SELECT u.id, u.username, u.email, u.role, u.is_blocked, u.email_verified, u.created_at, u.updated_at,
                p.first_name, p.last_name, p.profile_image, p.biography, p.motto
         FROM users u
         LEFT JOIN profiles p ON u.id = p.user_id
-        WHERE u.id = ?
+        WHERE u.id = ? AND u.deleted_at IS NULL
     `, id).Scan(
-        &user.ID, &user.Username, &user.Email, &user.Role, &user.IsBlocked, &user.CreatedAt, &user.UpdatedAt,
+        &user.ID, &user.Username, &user.Email, &user.Role, &user.IsBlocked, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
         &firstName, &lastName, &profileImage, &biography, &motto,
     )
 
@@ -405,16 +585,7 @@ func getUserProfile(c *gin.Context) {
         return
     }
 
-    var profile Profile
-    var firstName, lastName, profileImage, biography, motto sql.NullString
-    var createdAt, updatedAt time.Time
-    var profileID, userID int
-
-    err = db.QueryRow(
-        "SELECT id, user_id, first_name, last_name, profile_image, biography, motto, created_at, updated_at FROM profiles WHERE user_id = ?",
-        id,
-    ).Scan(&profileID, &userID, &firstName, &lastName, &profileImage, &biography, &motto, &createdAt, &updatedAt)
-
+    profile, err := loadProfile(id)
     if err != nil {
         if err == sql.ErrNoRows {
             c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
@@ -425,20 +596,60 @@ func getUserProfile(c *gin.Context) {
         return
     }
 
+    c.JSON(http.StatusOK, gin.H{"profile": profile})
+}
+
+// loadProfile reads the profile of a user, sql.ErrNoRows if there is none
+func loadProfile(userID int) (*Profile, error) {
+    var profile Profile
+    var firstName, lastName, profileImage, biography, motto sql.NullString
+
+    err := db.QueryRow(
+        "SELECT id, user_id, first_name, last_name, profile_image, biography, motto, created_at, updated_at FROM profiles WHERE user_id = ?",
+        userID,
+    ).Scan(&profile.ID, &profile.UserID, &firstName, &lastName, &profileImage, &biography, &motto, &profile.CreatedAt, &profile.UpdatedAt)
+    if err != nil {
+        return nil, err
+    }
+
     // Assign values, handling NULL cases
-    profile.ID = profileID
-    profile.UserID = userID
     profile.FirstName = firstName.String
     profile.LastName = lastName.String
     profile.ProfileImage = profileImage.String
     profile.Biography = biography.String
     profile.Motto = motto.String
-    profile.CreatedAt = createdAt
-    profile.UpdatedAt = updatedAt
+    return &profile, nil
+}
 
-    c.JSON(http.StatusOK, gin.H{"profile": profile})
+// validProfileImage accepts an empty value or an absolute http(s) URL,
+// such as the ones POST /content/media returns
+func validProfileImage(value string) bool {
+    if value == "" {
+        return true
+    }
+    parsed, err := url.Parse(value)
+    if err != nil {
+        return false
+    }
+    return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// respondUpdatedProfile returns the profile as stored after an update
+func respondUpdatedProfile(c *gin.Context, userID int) {
+    profile, err := loadProfile(userID)
+    if err != nil {
+        log.Printf("Error querying profile: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Profile updated successfully",
+        "profile": profile,
+    })
 }
 
+// PUT /users/:id/profile - replace the whole profile, omitted fields are cleared
 func updateUserProfile(c *gin.Context) {
     idStr := c.Param("id")
     id, err := strconv.Atoi(idStr)
@@ -452,6 +663,10 @@ func updateUserProfile(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
+    if !validProfileImage(req.ProfileImage) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "profile_image must be an http or https URL"})
+        return
+    }
 
     // Check if profile exists
     var count int
@@ -479,17 +694,111 @@ func updateUserProfile(c *gin.Context) {
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
+    respondUpdatedProfile(c, id)
 }
 
+// PATCH /users/:id/profile - update only the fields present in the body
+func patchUserProfile(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+        return
+    }
+
+    var req PatchProfileRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if req.ProfileImage != nil && !validProfileImage(*req.ProfileImage) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "profile_image must be an http or https URL"})
+        return
+    }
+
+    var sets []string
+    var args []interface{}
+    for _, field := range []struct {
+        column string
+        value  *string
+    }{
+        {"first_name", req.FirstName},
+        {"last_name", req.LastName},
+        {"profile_image", req.ProfileImage},
+        {"biography", req.Biography},
+        {"motto", req.Motto},
+    } {
+        if field.value != nil {
+            sets = append(sets, field.column+" = ?")
+            args = append(args, *field.value)
+        }
+    }
+    if len(sets) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+        return
+    }
+
+    var count int
+    if err := db.QueryRow("SELECT COUNT(*) FROM profiles WHERE user_id = ?", id).Scan(&count); err != nil {
+        log.Printf("Error checking profile: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+    if count == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+        return
+    }
+
+    _, err = db.Exec(
+        "UPDATE profiles SET "+strings.Join(sets, ", ")+", updated_at = NOW() WHERE user_id = ?",
+        append(args, id)...,
+    )
+    if err != nil {
+        log.Printf("Error updating profile: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+        return
+    }
+
+    respondUpdatedProfile(c, id)
+}
+
+// GET /profiles?page=&limit=&role=&q=&sort=&order=
 func getProfiles(c *gin.Context) {
-    rows, err := db.Query(`
-        SELECT p.id, p.user_id, p.first_name, p.last_name, p.profile_image, p.biography, p.motto, p.created_at, p.updated_at,
-               u.username, u.role
+    page := parsePagination(c, 20, 100)
+
+    filter, args, errMsg := userFilters(c, "u", false)
+    if errMsg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+        return
+    }
+
+    orderBy, ok := sortClause(c, map[string]string{
+        "updated_at": "p.updated_at",
+        "name":       "p.first_name, p.last_name",
+        "username":   "u.username",
+    }, "updated_at", "p.id")
+    if !ok {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort parameters"})
+        return
+    }
+
+    from := `
         FROM profiles p
         JOIN users u ON p.user_id = u.id
-        ORDER BY p.updated_at DESC
-    `)
+        WHERE u.deleted_at IS NULL` + filter
+
+    var total int64
+    if err := db.QueryRow("SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+        log.Printf("Error counting profiles: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+
+    rows, err := db.Query(`
+        SELECT p.id, p.user_id, p.first_name, p.last_name, p.profile_image, p.biography, p.motto, p.created_at, p.updated_at,
+               u.username, u.role`+
+        from+orderBy+" LIMIT ? OFFSET ?",
+        append(args, page.Limit, page.Offset())...,
+    )
     if err != nil {
         log.Printf("Error querying profiles: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -501,10 +810,11 @@ func getProfiles(c *gin.Context) {
     for rows.Next() {
         var profile Profile
         var username, role string
+        var firstName, lastName, profileImage, biography, motto sql.NullString
 
         err := rows.Scan(
-            &profile.ID, &profile.UserID, &profile.FirstName, &profile.LastName, 
-            &profile.ProfileImage, &profile.Biography, &profile.Motto, 
+            &profile.ID, &profile.UserID, &firstName, &lastName, 
+            &profileImage, &biography, &motto, 
             &profile.CreatedAt, &profile.UpdatedAt, &username, &role,
         )
         if err != nil {
@@ -512,6 +822,13 @@ func getProfiles(c *gin.Context) {
             continue
         }
 
+        // Profile columns are nullable, e.g. profile_image until one is set
+        profile.FirstName = firstName.String
+        profile.LastName = lastName.String
+        profile.ProfileImage = profileImage.String
+        profile.Biography = biography.String
+        profile.Motto = motto.String
+
         profileData := map[string]interface{}{
             "id":            profile.ID,
             "user_id":       profile.UserID,
@@ -530,8 +847,9 @@ func getProfiles(c *gin.Context) {
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "profiles": profiles,
-        "count":    len(profiles),
+        "profiles":   profiles,
+        "count":      len(profiles),
+        "pagination": page.Response(total),
     })
 }
 
@@ -554,7 +872,7 @@ func followUser(c *gin.Context) {
 
     // Proveri da li target user postoji
     var count int
-    err = db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", targetUserID).Scan(&count)
+    err = db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ? AND deleted_at IS NULL", targetUserID).Scan(&count)
     if err != nil || count == 0 {
         c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
         return
@@ -626,26 +944,43 @@ func checkFollowing(c *gin.Context) {
     })
 }
 
-// GET /following
-func getFollowing(c *gin.Context) {
-    userID := c.GetInt("user_id")
+// Follow list directions, also used as the response key
+const (
+    followingList = "following"
+    followersList = "followers"
+)
 
-    query := `SELECT f.id, f.follower_id, f.following_id, f.created_at, 
-                     u.username, p.first_name, p.last_name
+// listFollows returns one page of the users userID follows or is followed by
+func listFollows(userID int, direction string, page pagination) ([]FollowWithUser, int64, error) {
+    // For "following" the listed users are f.following_id, for "followers" f.follower_id
+    matchColumn, joinColumn := "f.follower_id", "f.following_id"
+    if direction == followersList {
+        matchColumn, joinColumn = "f.following_id", "f.follower_id"
+    }
+
+    from := `
               FROM follows f 
-              JOIN users u ON f.following_id = u.id 
+              JOIN users u ON ` + joinColumn + ` = u.id 
               LEFT JOIN profiles p ON u.id = p.user_id
-              WHERE f.follower_id = ?
-              ORDER BY f.created_at DESC`
+              WHERE ` + matchColumn + ` = ? AND u.deleted_at IS NULL`
+
+    var total int64
+    if err := db.QueryRow("SELECT COUNT(*)"+from, userID).Scan(&total); err != nil {
+        return nil, 0, err
+    }
+
+    query := `SELECT f.id, f.follower_id, f.following_id, f.created_at, 
+                     u.username, p.first_name, p.last_name` + from + `
+              ORDER BY f.created_at DESC, f.id DESC
+              LIMIT ? OFFSET ?`
     
-    rows, err := db.Query(query, userID)
+    rows, err := db.Query(query, userID, page.Limit, page.Offset())
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-        return
+        return nil, 0, err
     }
     defer rows.Close()
 
-    var following []FollowWithUser
+    follows := []FollowWithUser{}
     for rows.Next() {
         var f FollowWithUser
         var firstName, lastName sql.NullString
@@ -659,54 +994,138 @@ func getFollowing(c *gin.Context) {
 
         f.FirstName = firstName.String
         f.LastName = lastName.String
-        following = append(following, f)
+        follows = append(follows, f)
+    }
+
+    return follows, total, rows.Err()
+}
+
+// respondFollowList writes a follow list page under the direction key
+func respondFollowList(c *gin.Context, userID int, direction string) {
+    page := parsePagination(c, 50, 100)
+
+    follows, total, err := listFollows(userID, direction, page)
+    if err != nil {
+        log.Printf("Error querying %s: %v", direction, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "following": following,
-        "count": len(following),
+        direction:    follows,
+        "count":      len(follows),
+        "pagination": page.Response(total),
     })
 }
 
-// GET /followers
-func getFollowers(c *gin.Context) {
-    userID := c.GetInt("user_id")
+// GET /following
+func getFollowing(c *gin.Context) {
+    respondFollowList(c, c.GetInt("user_id"), followingList)
+}
 
-    query := `SELECT f.id, f.follower_id, f.following_id, f.created_at, 
-                     u.username, p.first_name, p.last_name
-              FROM follows f 
-              JOIN users u ON f.follower_id = u.id 
-              LEFT JOIN profiles p ON u.id = p.user_id
-              WHERE f.following_id = ?
-              ORDER BY f.created_at DESC`
-    
-    rows, err := db.Query(query, userID)
+// GET /following/ids - IDs of every active user the caller follows, unpaginated
+// for services that filter by it (the content-service blog feed)
+func getFollowingIDs(c *gin.Context) {
+    rows, err := db.Query(`
+        SELECT f.following_id FROM follows f
+        JOIN users u ON f.following_id = u.id
+        WHERE f.follower_id = ? AND u.deleted_at IS NULL
+    `, c.GetInt("user_id"))
     if err != nil {
+        log.Printf("Error querying followed IDs: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
         return
     }
     defer rows.Close()
 
-    var followers []FollowWithUser
+    ids := []int{}
     for rows.Next() {
-        var f FollowWithUser
-        var firstName, lastName sql.NullString
-        
-        err := rows.Scan(&f.ID, &f.FollowerID, &f.FollowingID, &f.CreatedAt, 
-                        &f.Username, &firstName, &lastName)
-        if err != nil {
-            log.Printf("Error scanning follow: %v", err)
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            log.Printf("Error scanning followed ID: %v", err)
             continue
         }
+        ids = append(ids, id)
+    }
 
-        f.FirstName = firstName.String
-        f.LastName = lastName.String
-        followers = append(followers, f)
+    c.JSON(http.StatusOK, gin.H{"user_ids": ids, "count": len(ids)})
+}
+
+// GET /followers
+func getFollowers(c *gin.Context) {
+    respondFollowList(c, c.GetInt("user_id"), followersList)
+}
+
+// targetUserID parses :id and checks the user exists, writing the error response otherwise
+func targetUserID(c *gin.Context) (int, bool) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+        return 0, false
+    }
+
+    var count int
+    err = db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ? AND deleted_at IS NULL", id).Scan(&count)
+    if err != nil {
+        log.Printf("Error checking user: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return 0, false
+    }
+    if count == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+        return 0, false
+    }
+
+    return id, true
+}
+
+// GET /users/:id/following
+func getUserFollowing(c *gin.Context) {
+    if id, ok := targetUserID(c); ok {
+        respondFollowList(c, id, followingList)
+    }
+}
+
+// GET /users/:id/followers
+func getUserFollowers(c *gin.Context) {
+    if id, ok := targetUserID(c); ok {
+        respondFollowList(c, id, followersList)
+    }
+}
+
+// GET /users/:id/follow-stats - counts for any user, relation flags are
+// relative to the caller and false for anonymous requests
+func getFollowStats(c *gin.Context) {
+    id, ok := targetUserID(c)
+    if !ok {
+        return
+    }
+    callerID := c.GetInt("user_id")
+
+    var followersCount, followingCount int
+    var isFollowing, followsYou bool
+    err := db.QueryRow(`
+        SELECT
+            (SELECT COUNT(*) FROM follows f JOIN users u ON f.follower_id = u.id
+             WHERE f.following_id = ? AND u.deleted_at IS NULL),
+            (SELECT COUNT(*) FROM follows f JOIN users u ON f.following_id = u.id
+             WHERE f.follower_id = ? AND u.deleted_at IS NULL),
+            EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND following_id = ?),
+            EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND following_id = ?)
+    `, id, id, callerID, id, id, callerID).Scan(&followersCount, &followingCount, &isFollowing, &followsYou)
+    if err != nil {
+        log.Printf("Error querying follow stats: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "followers": followers,
-        "count": len(followers),
+        "user_id":         id,
+        "followers_count": followersCount,
+        "following_count": followingCount,
+        "is_following":    isFollowing,
+        "follows_you":     followsYou,
+        "is_mutual":       isFollowing && followsYou,
     })
 }
 
@@ -750,4 +1169,110 @@ func canComment(c *gin.Context) {
         "reason": reason,
         "author_id": authorID,
     })
-}
\ No newline at end of file
+}
+
+// POST /can-comment/batch - canComment for many (user, author) pairs in one query,
+// results are returned in request order
+func canCommentBatch(c *gin.Context) {
+    var req CanCommentBatchRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    // Own blogs need no lookup, the rest are checked with one row constructor IN
+    var placeholders []string
+    var args []interface{}
+    for _, pair := range req.Pairs {
+        if pair.UserID != pair.AuthorID {
+            placeholders = append(placeholders, "(?, ?)")
+            args = append(args, pair.UserID, pair.AuthorID)
+        }
+    }
+
+    following := make(map[CommentPermissionPair]bool)
+    if len(placeholders) > 0 {
+        query := "SELECT follower_id, following_id FROM follows WHERE (follower_id, following_id) IN (" +
+            strings.Join(placeholders, ", ") + ")"
+        rows, err := db.Query(query, args...)
+        if err != nil {
+            log.Printf("Error querying follows: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+            return
+        }
+        defer rows.Close()
+
+        for rows.Next() {
+            var pair CommentPermissionPair
+            if err := rows.Scan(&pair.UserID, &pair.AuthorID); err != nil {
+                log.Printf("Error scanning follow: %v", err)
+                continue
+            }
+            following[pair] = true
+        }
+        if err := rows.Err(); err != nil {
+            log.Printf("Error iterating follows: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+            return
+        }
+    }
+
+    results := make([]CommentPermission, len(req.Pairs))
+    for i, pair := range req.Pairs {
+        result := CommentPermission{UserID: pair.UserID, AuthorID: pair.AuthorID, Reason: "not_following"}
+        switch {
+        case pair.UserID == pair.AuthorID:
+            result.CanComment, result.Reason = true, "own_blog"
+        case following[pair]:
+            result.CanComment, result.Reason = true, "following_author"
+        }
+        results[i] = result
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "results": results,
+        "count":   len(results),
+    })
+}
+
+// POST /users/lookup - resolve usernames to user IDs, used for @mentions.
+// Unknown and deleted usernames are left out of the result.
+func lookupUsers(c *gin.Context) {
+    var req UserLookupRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    placeholders := strings.TrimSuffix(strings.Repeat("?,", len(req.Usernames)), ",")
+    args := make([]interface{}, len(req.Usernames))
+    for i, username := range req.Usernames {
+        args[i] = username
+    }
+
+    rows, err := db.Query(
+        "SELECT id, username FROM users WHERE deleted_at IS NULL AND username IN ("+placeholders+")",
+        args...,
+    )
+    if err != nil {
+        log.Printf("Error looking up users: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+        return
+    }
+    defer rows.Close()
+
+    users := []UserRef{}
+    for rows.Next() {
+        var user UserRef
+        if err := rows.Scan(&user.ID, &user.Username); err != nil {
+            log.Printf("Error scanning user: %v", err)
+            continue
+        }
+        users = append(users, user)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "users": users,
+        "count": len(users),
+    })
+}
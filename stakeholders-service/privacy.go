@@ -0,0 +1,402 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"stakeholders-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Personal data export and erasure. stakeholders-service owns the account, so
+// it orchestrates: content-service and commerce-service expose
+// /internal/users/:id/export and /internal/users/:id/data, called with the
+// caller's own token.
+
+// dataService is a service holding personal data of users
+type dataService struct {
+	name    string
+	baseURL string
+}
+
+func dataServices() []dataService {
+	services := []dataService{
+		{"content", os.Getenv("CONTENT_SERVICE_URL")},
+		{"commerce", os.Getenv("COMMERCE_SERVICE_URL")},
+	}
+	defaults := map[string]string{
+		"content":  "content-service:8082",
+		"commerce": "commerce-service:8083",
+	}
+	for i, s := range services {
+		if s.baseURL == "" {
+			s.baseURL = defaults[s.name]
+		}
+		if !strings.Contains(s.baseURL, "://") {
+			s.baseURL = "http://" + s.baseURL
+		}
+		services[i] = s
+	}
+	return services
+}
+
+var internalClient = &http.Client{Timeout: 30 * time.Second}
+
+// callDataService calls an internal endpoint and returns its JSON body
+func callDataService(ctx context.Context, service dataService, method, path, authHeader string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, method, service.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := internalClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s-service responded %d: %s", service.name, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// queryRows returns every row as a column name map, for export only
+func queryRows(query string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []gin.H{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := gin.H{}
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// exportStakeholdersData collects the account, profile, follows and sessions
+func exportStakeholdersData(userID int) (gin.H, error) {
+	queries := []struct {
+		key   string
+		query string
+	}{
+		{"user", `SELECT id, username, email, role, is_blocked, email_verified, email_verified_at,
+		                 totp_enabled, created_at, updated_at
+		          FROM users WHERE id = ?`},
+		{"profile", `SELECT first_name, last_name, profile_image, biography, motto, created_at, updated_at
+		             FROM profiles WHERE user_id = ?`},
+		{"following", `SELECT u.id AS user_id, u.username, f.created_at
+		               FROM follows f JOIN users u ON f.following_id = u.id
+		               WHERE f.follower_id = ? ORDER BY f.created_at`},
+		{"followers", `SELECT u.id AS user_id, u.username, f.created_at
+		               FROM follows f JOIN users u ON f.follower_id = u.id
+		               WHERE f.following_id = ? ORDER BY f.created_at`},
+		{"sessions", `SELECT id, created_at, expires_at, revoked_at
+		              FROM refresh_tokens WHERE user_id = ? ORDER BY created_at`},
+		{"blog_comments", `SELECT blog_id, comment_text, created_at, updated_at
+		                   FROM blog_comments WHERE user_id = ? ORDER BY created_at`},
+	}
+
+	data := gin.H{"service": "stakeholders-service", "user_id": userID, "exported_at": time.Now()}
+	for _, q := range queries {
+		rows, err := queryRows(q.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", q.key, err)
+		}
+		// One-row tables are exported as an object
+		if q.key == "user" || q.key == "profile" {
+			if len(rows) > 0 {
+				data[q.key] = rows[0]
+			} else {
+				data[q.key] = nil
+			}
+			continue
+		}
+		data[q.key] = rows
+	}
+	return data, nil
+}
+
+// GET /me/export - zip archive with one JSON file per service
+func exportMyData(c *gin.Context) {
+	userID, _ := auth.UserID(c)
+	username := c.GetString(auth.ContextUsername)
+
+	files := map[string]interface{}{}
+
+	stakeholdersData, err := exportStakeholdersData(userID)
+	if err != nil {
+		log.Printf("Error exporting user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	files["stakeholders.json"] = stakeholdersData
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	// The export is only useful when complete, any failing service fails it
+	for _, service := range dataServices() {
+		body, err := callDataService(ctx, service, http.MethodGet,
+			fmt.Sprintf("/internal/users/%d/export", userID), c.GetHeader("Authorization"))
+		if err != nil {
+			log.Printf("Error exporting user %d from %s: %v", userID, service.name, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to collect data from " + service.name + "-service"})
+			return
+		}
+		files[service.name+".json"] = body
+	}
+
+	generatedAt := time.Now()
+	files["manifest.json"] = gin.H{
+		"user_id":      userID,
+		"username":     username,
+		"generated_at": generatedAt,
+		"files":        []string{"stakeholders.json", "content.json", "commerce.json"},
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, name := range []string{"manifest.json", "stakeholders.json", "content.json", "commerce.json"} {
+		encoded, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			log.Printf("Error encoding %s: %v", name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build export"})
+			return
+		}
+		w, err := zw.Create(name)
+		if err == nil {
+			_, err = w.Write(encoded)
+		}
+		if err != nil {
+			log.Printf("Error writing %s to archive: %v", name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build export"})
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Error closing archive: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build export"})
+		return
+	}
+
+	filename := fmt.Sprintf("soa-tours-export-%d-%s.zip", userID, generatedAt.Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // TOTP or recovery code, required when 2FA is enabled
+}
+
+// DELETE /me - erase the caller's data everywhere and anonymize the account.
+// Other services are erased first, their endpoints are idempotent so a
+// failed deletion can simply be retried.
+func deleteMyAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, _ := auth.UserID(c)
+
+	var username, passwordHash string
+	var twoFactorEnabled bool
+	err := db.QueryRow(
+		"SELECT username, password_hash, totp_enabled FROM users WHERE id = ? AND deleted_at IS NULL",
+		userID,
+	).Scan(&username, &passwordHash, &twoFactorEnabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error querying user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if reauthThrottled(c, username) {
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil {
+		recordLoginFailure(username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	// The second factor is checked and burned in its own short transaction,
+	// no row locks are held while the other services erase their data
+	if twoFactorEnabled && !confirmSecondFactor(c, userID, username, req.Code) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	summaries := gin.H{}
+	for _, service := range dataServices() {
+		body, err := callDataService(ctx, service, http.MethodDelete,
+			fmt.Sprintf("/internal/users/%d/data", userID), c.GetHeader("Authorization"))
+		if err != nil {
+			log.Printf("Error erasing user %d in %s: %v", userID, service.name, err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":   "Failed to delete data in " + service.name + "-service, please try again",
+				"deleted": summaries,
+			})
+			return
+		}
+		summaries[service.name] = body
+	}
+
+	// The remote erase is idempotent, if this step fails the user can retry
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account", "deleted": summaries})
+		return
+	}
+	defer tx.Rollback()
+
+	local, err := anonymizeUser(tx, userID, username)
+	if err != nil {
+		log.Printf("Error anonymizing user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account", "deleted": summaries})
+		return
+	}
+	summaries["stakeholders"] = local
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account", "deleted": summaries})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your account and personal data have been deleted",
+		"deleted": summaries,
+	})
+}
+
+// confirmSecondFactor verifies a TOTP or recovery code and commits its use,
+// writing the error response when it is wrong
+func confirmSecondFactor(c *gin.Context, userID int, username, code string) bool {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	defer tx.Rollback()
+
+	ok, err := verifySecondFactor(tx, userID, code, len(strings.TrimSpace(code)) != 6)
+	if err != nil {
+		log.Printf("Error verifying second factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if !ok {
+		tx.Rollback()
+		recordLoginFailure(username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return false
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	return true
+}
+
+// anonymizeUser strips the account of personal data. The row itself stays,
+// soft deleted, so ids referenced by audit logs and other services resolve.
+func anonymizeUser(tx *sql.Tx, userID int, username string) (gin.H, error) {
+	summary := gin.H{}
+
+	statements := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{"profile", "DELETE FROM profiles WHERE user_id = ?", []interface{}{userID}},
+		{"follows", "DELETE FROM follows WHERE follower_id = ? OR following_id = ?", []interface{}{userID, userID}},
+		{"blog_comments", "DELETE FROM blog_comments WHERE user_id = ?", []interface{}{userID}},
+		{"sessions", "DELETE FROM refresh_tokens WHERE user_id = ?", []interface{}{userID}},
+		{"account_tokens", "DELETE FROM account_tokens WHERE user_id = ?", []interface{}{userID}},
+		{"recovery_codes", "DELETE FROM recovery_codes WHERE user_id = ?", []interface{}{userID}},
+		{"login_challenges", "DELETE FROM login_challenges WHERE user_id = ?", []interface{}{userID}},
+		{"login_lockouts", "DELETE FROM login_lockouts WHERE user_id = ?", []interface{}{userID}},
+		{"login_failures", "DELETE FROM login_failures WHERE scope = ? AND scope_key = ?",
+			[]interface{}{throttleScopeUsername, normalizeUsername(username)}},
+	}
+	for _, s := range statements {
+		result, err := tx.Exec(s.query, s.args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+		summary[s.name+"_deleted"], _ = result.RowsAffected()
+	}
+
+	// "!" is never a valid bcrypt hash, so no password can match it
+	_, err := tx.Exec(`
+		UPDATE users SET
+			username = CONCAT('deleted_user_', id),
+			email = CONCAT('deleted_', id, '@deleted.invalid'),
+			password_hash = '!',
+			email_verified = FALSE, email_verified_at = NULL,
+			totp_secret = NULL, totp_enabled = FALSE, totp_last_step = NULL,
+			deleted_at = COALESCE(deleted_at, NOW())
+		WHERE id = ?
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user: %w", err)
+	}
+	summary["user_anonymized"] = true
+
+	return summary, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recommendation reasons
+const (
+	reasonFollowedByFollowees = "followed_by_people_you_follow"
+	reasonPopular             = "popular"
+)
+
+type FollowRecommendation struct {
+	UserID       int      `json:"user_id"`
+	Username     string   `json:"username"`
+	Role         string   `json:"role"`
+	FirstName    string   `json:"first_name"`
+	LastName     string   `json:"last_name"`
+	ProfileImage string   `json:"profile_image"`
+	MutualCount  int      `json:"mutual_count"`          // how many of my followees follow them
+	FollowedBy   []string `json:"followed_by,omitempty"` // up to 3 of those followees
+	FollowsYou   bool     `json:"follows_you"`
+	Reason       string   `json:"reason"`
+}
+
+// Candidates are never me, people I already follow, blocked or deleted users
+const recommendationExclusions = `
+	u.id <> ?
+	AND u.is_blocked = FALSE AND u.deleted_at IS NULL
+	AND NOT EXISTS (SELECT 1 FROM follows mine WHERE mine.follower_id = ? AND mine.following_id = u.id)`
+
+// GET /recommendations/follow?limit=&role= - "people you may know"
+// Friends-of-friends ranked by how many of my followees follow them, topped up
+// with the most followed users so new accounts without follows get suggestions too.
+func recommendFollows(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		}
+	}
+
+	roleFilter := ""
+	var roleArgs []interface{}
+	if role := c.Query("role"); role != "" {
+		if role != "admin" && role != "guide" && role != "tourist" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role filter"})
+			return
+		}
+		roleFilter = " AND u.role = ?"
+		roleArgs = append(roleArgs, role)
+	}
+
+	args := []interface{}{userID, userID, userID, userID}
+	args = append(args, roleArgs...)
+	args = append(args, limit)
+
+	rows, err := db.Query(`
+		SELECT u.id, u.username, u.role, p.first_name, p.last_name, p.profile_image,
+		       COUNT(DISTINCT f1.following_id) AS mutual_count,
+		       SUBSTRING_INDEX(GROUP_CONCAT(DISTINCT mu.username ORDER BY mu.username SEPARATOR ','), ',', 3) AS followed_by,
+		       EXISTS (SELECT 1 FROM follows fb WHERE fb.follower_id = u.id AND fb.following_id = ?) AS follows_you
+		FROM follows f1
+		JOIN follows f2 ON f2.follower_id = f1.following_id
+		JOIN users u ON u.id = f2.following_id
+		JOIN users mu ON mu.id = f1.following_id
+		LEFT JOIN profiles p ON p.user_id = u.id
+		WHERE f1.follower_id = ? AND`+recommendationExclusions+roleFilter+`
+		GROUP BY u.id, u.username, u.role, p.first_name, p.last_name, p.profile_image
+		ORDER BY mutual_count DESC, follows_you DESC, u.id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		log.Printf("Error querying follow recommendations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	recommendations := []FollowRecommendation{}
+	for rows.Next() {
+		var r FollowRecommendation
+		var firstName, lastName, profileImage, followedBy sql.NullString
+		if err := rows.Scan(&r.UserID, &r.Username, &r.Role, &firstName, &lastName, &profileImage,
+			&r.MutualCount, &followedBy, &r.FollowsYou); err != nil {
+			log.Printf("Error scanning recommendation: %v", err)
+			continue
+		}
+		r.FirstName = firstName.String
+		r.LastName = lastName.String
+		r.ProfileImage = profileImage.String
+		if followedBy.String != "" {
+			r.FollowedBy = strings.Split(followedBy.String, ",")
+		}
+		r.Reason = reasonFollowedByFollowees
+		recommendations = append(recommendations, r)
+	}
+	rows.Close()
+
+	if remaining := limit - len(recommendations); remaining > 0 {
+		popular, err := popularRecommendations(userID, remaining, roleFilter, roleArgs, recommendations)
+		if err != nil {
+			log.Printf("Error querying popular users: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		recommendations = append(recommendations, popular...)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recommendations": recommendations,
+		"count":           len(recommendations),
+	})
+}
+
+// popularRecommendations returns the most followed users not already suggested
+func popularRecommendations(userID, limit int, roleFilter string, roleArgs []interface{}, already []FollowRecommendation) ([]FollowRecommendation, error) {
+	args := []interface{}{userID, userID, userID}
+	args = append(args, roleArgs...)
+
+	excludeSuggested := ""
+	if len(already) > 0 {
+		placeholders := make([]string, len(already))
+		for i, r := range already {
+			placeholders[i] = "?"
+			args = append(args, r.UserID)
+		}
+		excludeSuggested = fmt.Sprintf(" AND u.id NOT IN (%s)", strings.Join(placeholders, ", "))
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(`
+		SELECT u.id, u.username, u.role, p.first_name, p.last_name, p.profile_image,
+		       EXISTS (SELECT 1 FROM follows fb WHERE fb.follower_id = u.id AND fb.following_id = ?) AS follows_you,
+		       COUNT(f.id) AS follower_count
+		FROM users u
+		LEFT JOIN profiles p ON p.user_id = u.id
+		LEFT JOIN follows f ON f.following_id = u.id
+		WHERE`+recommendationExclusions+roleFilter+excludeSuggested+`
+		GROUP BY u.id, u.username, u.role, p.first_name, p.last_name, p.profile_image
+		ORDER BY follower_count DESC, u.id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var popular []FollowRecommendation
+	for rows.Next() {
+		var r FollowRecommendation
+		var firstName, lastName, profileImage sql.NullString
+		var followerCount int
+		if err := rows.Scan(&r.UserID, &r.Username, &r.Role, &firstName, &lastName, &profileImage,
+			&r.FollowsYou, &followerCount); err != nil {
+			log.Printf("Error scanning recommendation: %v", err)
+			continue
+		}
+		r.FirstName = firstName.String
+		r.LastName = lastName.String
+		r.ProfileImage = profileImage.String
+		r.Reason = reasonPopular
+		popular = append(popular, r)
+	}
+	return popular, rows.Err()
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"stakeholders-service/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Refresh tokens are opaque random strings, only their SHA-256 hash is stored
+const refreshTokenTTL = 7 * 24 * time.Hour
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type tokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// hashToken is the stored form of opaque tokens (refresh and account tokens)
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// createSession stores a new refresh token and issues the access token bound to it
+func createSession(exec execer, user User) (*tokenPair, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	refreshToken := hex.EncodeToString(raw)
+	refreshExpiresAt := time.Now().Add(refreshTokenTTL)
+
+	result, err := exec.Exec(
+		"INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES (?, ?, ?)",
+		user.ID, hashToken(refreshToken), refreshExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresAt, err := tokenIssuer.Issue(auth.Claims{
+		UserID:        user.ID,
+		Username:      user.Username,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		SessionID:     sessionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// isSessionActive is the verifier session check - the refresh token behind the
+// access token must not be revoked and its user must not be blocked, so a
+// revoke or block takes effect on the next request. content-service and
+// commerce-service ask through GET /internal/sessions/:id.
+func isSessionActive(claims *auth.Claims) (bool, error) {
+	if claims.SessionID == 0 {
+		return false, nil
+	}
+
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM refresh_tokens rt
+		JOIN users u ON rt.user_id = u.id
+		WHERE rt.id = ? AND rt.user_id = ? AND rt.revoked_at IS NULL
+		  AND u.is_blocked = FALSE AND u.deleted_at IS NULL
+	`, claims.SessionID, claims.UserID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GET /internal/sessions/:id?user_id= - whether a session is active, for the
+// other services' token checks
+func getSessionStatus(c *gin.Context) {
+	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	userID, err := strconv.Atoi(c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	active, err := isSessionActive(&auth.Claims{UserID: userID, SessionID: sessionID})
+	if err != nil {
+		log.Printf("Error checking session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"active": active})
+}
+
+// revokeUserSessions revokes every active refresh token of the user
+func revokeUserSessions(exec execer, userID int) (int64, error) {
+	result, err := exec.Exec(
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// POST /auth/refresh - rotate refresh token and issue a new access token
+func refreshSession(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sessionID int64
+	var user User
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT rt.id, rt.expires_at, rt.revoked_at, u.id, u.username, u.email, u.role, u.is_blocked, u.email_verified
+		FROM refresh_tokens rt
+		JOIN users u ON rt.user_id = u.id
+		WHERE rt.token_hash = ? AND u.deleted_at IS NULL
+	`, hashToken(req.RefreshToken)).Scan(
+		&sessionID, &expiresAt, &revokedAt, &user.ID, &user.Username, &user.Email, &user.Role, &user.IsBlocked,
+		&user.EmailVerified,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		log.Printf("Error querying refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// A rotated token being presented again means it leaked - end every session of the user
+	if revokedAt.Valid {
+		if _, err := revokeUserSessions(db, user.ID); err != nil {
+			log.Printf("Error revoking sessions after refresh token reuse: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	if time.Now().After(expiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+		return
+	}
+
+	if user.IsBlocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is blocked"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Revoke the old token, the conditional update guards against concurrent rotation
+	result, err := tx.Exec(
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL",
+		sessionID,
+	)
+	if err != nil {
+		log.Printf("Error revoking refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	tokens, err := createSession(tx, user)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// POST /auth/logout - revoke the given refresh token
+func logoutUser(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err := db.Exec(
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = ? AND revoked_at IS NULL",
+		hashToken(req.RefreshToken),
+	)
+	if err != nil {
+		log.Printf("Error revoking refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	// Unknown or already revoked tokens are not reported, logout is idempotent
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// POST /users/:id/sessions/revoke - admin ends every session of a user
+func revokeSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	adminID, _ := auth.UserID(c)
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", userID).Scan(&count); err != nil {
+		log.Printf("Error checking user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	revoked, err := revokeUserSessions(tx, userID)
+	if err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	if err = recordAudit(tx, adminID, userID, auditRevokeSessions, c.Query("reason"), gin.H{"revoked_sessions": revoked}); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write audit log"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Sessions revoked successfully",
+		"user_id":          userID,
+		"revoked_sessions": revoked,
+	})
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stakeholders-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Failed logins are counted per username and per client IP. After a few
+// failures each further attempt waits twice as long, past the lockout
+// threshold the key is locked for a while and the lockout is recorded.
+// Unknown usernames are counted the same way so responses never reveal
+// whether an account exists. Password reset requests are counted the same
+// way per email and IP, so the endpoint cannot flood a mailbox.
+const (
+	throttleScopeUsername   = "username"
+	throttleScopeIP         = "ip"
+	throttleScopeResetEmail = "reset_email"
+	throttleScopeResetIP    = "reset_ip"
+
+	// Failures older than this no longer count
+	failureWindow = 15 * time.Minute
+)
+
+type throttlePolicy struct {
+	backoffAfter int           // failures before backoff starts
+	maxBackoff   time.Duration // cap of the doubling delay
+	lockoutAfter int           // failures that lock the key
+	lockoutFor   time.Duration
+}
+
+var throttlePolicies = map[string]throttlePolicy{
+	throttleScopeUsername: {backoffAfter: 3, maxBackoff: time.Minute, lockoutAfter: 10, lockoutFor: 15 * time.Minute},
+	// Higher limits since many users can share an address
+	throttleScopeIP: {backoffAfter: 10, maxBackoff: time.Minute, lockoutAfter: 50, lockoutFor: 30 * time.Minute},
+	// Every reset request counts, a few mails per address are plenty
+	throttleScopeResetEmail: {backoffAfter: 2, maxBackoff: 5 * time.Minute, lockoutAfter: 5, lockoutFor: time.Hour},
+	throttleScopeResetIP:    {backoffAfter: 5, maxBackoff: 5 * time.Minute, lockoutAfter: 20, lockoutFor: time.Hour},
+}
+
+// delay returns how long a key with failedCount failures is locked, 0 if it is not
+func (p throttlePolicy) delay(failedCount int) time.Duration {
+	switch {
+	case failedCount >= p.lockoutAfter:
+		return p.lockoutFor
+	case failedCount >= p.backoffAfter:
+		// Doubling stops at the cap, shifting further would overflow
+		delay := time.Second
+		for doublings := failedCount - p.backoffAfter; doublings > 0 && delay < p.maxBackoff; doublings-- {
+			delay *= 2
+		}
+		if delay > p.maxBackoff {
+			delay = p.maxBackoff
+		}
+		return delay
+	default:
+		return 0
+	}
+}
+
+type throttleKey struct{ scope, value string }
+
+// dummyPasswordHash is compared against for unknown usernames so both cases take as long
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+type LoginLockout struct {
+	ID          int       `json:"id"`
+	Scope       string    `json:"scope"`
+	ScopeKey    string    `json:"scope_key"`
+	UserID      *int      `json:"user_id,omitempty"`
+	IPAddress   string    `json:"ip_address"`
+	FailedCount int       `json:"failed_count"`
+	LockedUntil time.Time `json:"locked_until"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// loginRetryAfter returns how long the username or IP must wait, 0 if it may try now
+func loginRetryAfter(username, ip string) (time.Duration, error) {
+	return throttleRetryAfter(
+		throttleKey{throttleScopeUsername, normalizeUsername(username)},
+		throttleKey{throttleScopeIP, ip},
+	)
+}
+
+// throttleRetryAfter returns the longest wait of the keys, 0 if none is throttled
+func throttleRetryAfter(keys ...throttleKey) (time.Duration, error) {
+	var conditions []string
+	var args []interface{}
+	for _, key := range keys {
+		conditions = append(conditions, "(scope = ? AND scope_key = ?)")
+		args = append(args, key.scope, key.value)
+	}
+
+	var seconds int64
+	err := db.QueryRow(`
+		SELECT COALESCE(MAX(TIMESTAMPDIFF(SECOND, NOW(), locked_until)) + 1, 0)
+		FROM login_failures
+		WHERE locked_until > NOW() AND (`+strings.Join(conditions, " OR ")+`)
+	`, args...).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// recordLoginFailure counts a failure for both keys and applies backoff or lockout
+func recordLoginFailure(username, ip string) {
+	username = normalizeUsername(username)
+	for _, key := range []throttleKey{
+		{throttleScopeUsername, username},
+		{throttleScopeIP, ip},
+	} {
+		if err := countFailure(key.scope, key.value, username, ip); err != nil {
+			log.Printf("Error recording failed login for %s: %v", key.scope, err)
+		}
+	}
+}
+
+func countFailure(scope, key, username, ip string) error {
+	policy := throttlePolicies[scope]
+
+	// failed_count is assigned first so the window check sees the previous failure time
+	_, err := db.Exec(`
+		INSERT INTO login_failures (scope, scope_key, failed_count, last_failed_at)
+		VALUES (?, ?, 1, NOW())
+		ON DUPLICATE KEY UPDATE
+			failed_count = IF(last_failed_at < NOW() - INTERVAL ? SECOND, 1, failed_count + 1),
+			last_failed_at = NOW()
+	`, scope, key, int(failureWindow.Seconds()))
+	if err != nil {
+		return err
+	}
+
+	var failedCount int
+	err = db.QueryRow(
+		"SELECT failed_count FROM login_failures WHERE scope = ? AND scope_key = ?",
+		scope, key,
+	).Scan(&failedCount)
+	if err != nil {
+		return err
+	}
+
+	delay := policy.delay(failedCount)
+	if delay == 0 {
+		return nil
+	}
+
+	_, err = db.Exec(
+		"UPDATE login_failures SET locked_until = NOW() + INTERVAL ? SECOND WHERE scope = ? AND scope_key = ?",
+		int(delay.Seconds()), scope, key,
+	)
+	if err != nil || failedCount < policy.lockoutAfter {
+		return err
+	}
+
+	// Username lockouts are linked to the account when it exists
+	var lockedUsername interface{}
+	if scope == throttleScopeUsername {
+		lockedUsername = username
+	}
+
+	log.Printf("Login lockout: %s %q after %d failed attempts", scope, key, failedCount)
+	_, err = db.Exec(`
+		INSERT INTO login_lockouts (scope, scope_key, user_id, ip_address, failed_count, locked_until)
+		VALUES (?, ?, (SELECT id FROM users WHERE username = ? AND deleted_at IS NULL), ?, ?, NOW() + INTERVAL ? SECOND)
+	`, scope, key, lockedUsername, ip, failedCount, int(delay.Seconds()))
+	return err
+}
+
+// clearLoginFailures forgets the failures of a username after a successful
+// login. IP failures stay, one valid account must not reset an attacker's address.
+func clearLoginFailures(username string) {
+	_, err := db.Exec(
+		"DELETE FROM login_failures WHERE scope = ? AND scope_key = ?",
+		throttleScopeUsername, normalizeUsername(username),
+	)
+	if err != nil {
+		log.Printf("Error clearing failed logins: %v", err)
+	}
+}
+
+// recordPasswordResetRequest counts a reset request for the email and IP
+func recordPasswordResetRequest(email, ip string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, key := range []throttleKey{
+		{throttleScopeResetEmail, email},
+		{throttleScopeResetIP, ip},
+	} {
+		if err := countFailure(key.scope, key.value, "", ip); err != nil {
+			log.Printf("Error recording password reset request for %s: %v", key.scope, err)
+		}
+	}
+}
+
+// reauthThrottled answers 429 and returns true when the username or client IP
+// is throttled. Passwords and codes re-entered while signed in count like
+// logins, so a stolen access token gives no unlimited guesses either.
+func reauthThrottled(c *gin.Context, username string) bool {
+	retryAfter, err := loginRetryAfter(username, c.ClientIP())
+	if err != nil {
+		log.Printf("Error checking login throttle: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return true
+	}
+	if retryAfter > 0 {
+		tooManyAttempts(c, retryAfter)
+		return true
+	}
+	return false
+}
+
+// tooManyAttempts writes the throttled response, identical for known and unknown usernames
+func tooManyAttempts(c *gin.Context, retryAfter time.Duration) {
+	tooManyRequests(c, retryAfter, "Too many failed login attempts, try again later")
+}
+
+func tooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(retryAfter.Seconds())
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
+
+// GET /admin/lockouts?scope=&active=&user_id=&page=&limit= - recorded login lockouts
+func getLoginLockouts(c *gin.Context) {
+	page := parsePagination(c, 20, 100)
+
+	where := "WHERE 1 = 1"
+	var args []interface{}
+	if scope := c.Query("scope"); scope != "" {
+		if _, ok := throttlePolicies[scope]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope filter"})
+			return
+		}
+		where += " AND scope = ?"
+		args = append(args, scope)
+	}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		where += " AND user_id = ?"
+		args = append(args, userID)
+	}
+	if activeStr := c.Query("active"); activeStr != "" {
+		active, err := strconv.ParseBool(activeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid active filter"})
+			return
+		}
+		if active {
+			where += " AND locked_until > NOW()"
+		} else {
+			where += " AND locked_until <= NOW()"
+		}
+	}
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM login_lockouts "+where, args...).Scan(&total); err != nil {
+		log.Printf("Error counting lockouts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	rows, err := db.Query(
+		"SELECT id, scope, scope_key, user_id, ip_address, failed_count, locked_until, locked_until > NOW(), created_at FROM login_lockouts "+
+			where+" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",
+		append(args, page.Limit, page.Offset())...,
+	)
+	if err != nil {
+		log.Printf("Error querying lockouts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	lockouts := []LoginLockout{}
+	for rows.Next() {
+		var l LoginLockout
+		var userID sql.NullInt64
+		if err := rows.Scan(&l.ID, &l.Scope, &l.ScopeKey, &userID, &l.IPAddress, &l.FailedCount,
+			&l.LockedUntil, &l.Active, &l.CreatedAt); err != nil {
+			log.Printf("Error scanning lockout: %v", err)
+			continue
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			l.UserID = &id
+		}
+		lockouts = append(lockouts, l)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lockouts":   lockouts,
+		"pagination": page.Response(total),
+	})
+}
+
+// POST /admin/users/:id/unlock-login - clear failed logins of a user's username
+func unlockLogin(c *gin.Context) {
+	user, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	adminID, _ := auth.UserID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"DELETE FROM login_failures WHERE scope = ? AND scope_key = ?",
+		throttleScopeUsername, normalizeUsername(user.Username),
+	)
+	if err != nil {
+		log.Printf("Error clearing failed logins: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock login"})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE login_lockouts SET locked_until = NOW() WHERE user_id = ? AND locked_until > NOW()", user.ID)
+	if err != nil {
+		log.Printf("Error ending lockouts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock login"})
+		return
+	}
+
+	if err = recordAudit(tx, adminID, user.ID, auditUnlockLogin, c.Query("reason"), nil); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write audit log"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login unlocked successfully",
+		"user_id": user.ID,
+	})
+}
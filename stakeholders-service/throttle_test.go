@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyDelay(t *testing.T) {
+	policy := throttlePolicy{backoffAfter: 3, maxBackoff: time.Minute, lockoutAfter: 10, lockoutFor: 15 * time.Minute}
+
+	tests := []struct {
+		failedCount int
+		want        time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{8, 32 * time.Second},
+		{9, time.Minute},
+		{10, 15 * time.Minute},
+		{50, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.delay(tt.failedCount); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failedCount, got, tt.want)
+		}
+	}
+}
+
+// Long backoff ranges must stay at the cap instead of overflowing
+func TestThrottlePolicyDelayIPScope(t *testing.T) {
+	policy := throttlePolicies[throttleScopeIP]
+	for failedCount := 40; failedCount < policy.lockoutAfter; failedCount++ {
+		if got := policy.delay(failedCount); got != policy.maxBackoff {
+			t.Errorf("delay(%d) = %v, want %v", failedCount, got, policy.maxBackoff)
+		}
+	}
+	if got := policy.delay(policy.lockoutAfter); got != policy.lockoutFor {
+		t.Errorf("delay(%d) = %v, want %v", policy.lockoutAfter, got, policy.lockoutFor)
+	}
+}
+
+func TestThrottlePoliciesLockOutAfterBackoff(t *testing.T) {
+	for scope, policy := range throttlePolicies {
+		if policy.backoffAfter <= 0 || policy.lockoutAfter <= policy.backoffAfter {
+			t.Errorf("%s: lockout after %d must come after backoff after %d", scope, policy.lockoutAfter, policy.backoffAfter)
+		}
+		if policy.lockoutFor < policy.maxBackoff {
+			t.Errorf("%s: lockout of %v is shorter than the backoff cap %v", scope, policy.lockoutFor, policy.maxBackoff)
+		}
+	}
+}
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"stakeholders-service/auth"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Optional TOTP two-factor authentication for guides and admins
+const (
+	totpIssuerName          = "SOA Tours"
+	recoveryCodeCount       = 10
+	loginChallengeTTL       = 5 * time.Minute
+	maxChallengeAttempts    = 5
+	twoFactorMethodTOTP     = "totp"
+	twoFactorMethodRecovery = "recovery_code"
+)
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP or recovery code
+}
+
+type LoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// normalizeRecoveryCode lets users type codes with or without the dash and in any case
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// replaceRecoveryCodes drops the old codes and returns new ones, only their
+// hashes are stored so they are shown to the user once
+func replaceRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(raw)
+		codes[i] = code[:5] + "-" + code[5:]
+
+		_, err := tx.Exec(
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)",
+			userID, hashToken(normalizeRecoveryCode(code)),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code when useRecovery is
+// set, for an enrolled user and burns what was used. It runs inside tx with
+// the user row locked so concurrent attempts cannot reuse a code.
+func verifySecondFactor(tx *sql.Tx, userID int, code string, useRecovery bool) (bool, error) {
+	if useRecovery {
+		result, err := tx.Exec(
+			"UPDATE recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+			userID, hashToken(normalizeRecoveryCode(code)),
+		)
+		if err != nil {
+			return false, err
+		}
+		rowsAffected, err := result.RowsAffected()
+		return rowsAffected > 0, err
+	}
+
+	var secret sql.NullString
+	var lastStep sql.NullInt64
+	err := tx.QueryRow(
+		"SELECT totp_secret, totp_last_step FROM users WHERE id = ? AND totp_enabled = TRUE FOR UPDATE",
+		userID,
+	).Scan(&secret, &lastStep)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := auth.ValidateTOTP(secret.String, code, time.Now(), lastStep.Int64)
+	if !ok {
+		return false, nil
+	}
+
+	_, err = tx.Exec("UPDATE users SET totp_last_step = ? WHERE id = ?", step, userID)
+	return err == nil, err
+}
+
+// POST /auth/2fa/setup - start enrollment with a new secret, it is only
+// enforced after /auth/2fa/confirm
+func setupTwoFactor(c *gin.Context) {
+	userID, _ := auth.UserID(c)
+
+	var username string
+	var enabled bool
+	err := db.QueryRow(
+		"SELECT username, totp_enabled FROM users WHERE id = ? AND deleted_at IS NULL",
+		userID,
+	).Scan(&username, &enabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error querying user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup"})
+		return
+	}
+
+	_, err = db.Exec(
+		"UPDATE users SET totp_secret = ?, totp_last_step = NULL WHERE id = ? AND totp_enabled = FALSE",
+		secret, userID,
+	)
+	if err != nil {
+		log.Printf("Error storing TOTP secret: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Scan the URI with an authenticator app, then confirm with a code",
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(secret, totpIssuerName, username),
+	})
+}
+
+// POST /auth/2fa/confirm - enable 2FA with a code from the pending secret and
+// return the recovery codes
+func confirmTwoFactor(c *gin.Context) {
+	var req ConfirmTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, _ := auth.UserID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	var secret sql.NullString
+	var enabled bool
+	err = tx.QueryRow(
+		"SELECT totp_secret, totp_enabled FROM users WHERE id = ? AND deleted_at IS NULL FOR UPDATE",
+		userID,
+	).Scan(&secret, &enabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error querying user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if !secret.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor setup first"})
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(secret.String, req.Code, time.Now(), 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE users SET totp_enabled = TRUE, totp_last_step = ? WHERE id = ?",
+		step, userID,
+	)
+	if err != nil {
+		log.Printf("Error enabling two-factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		log.Printf("Error creating recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled, store the recovery codes somewhere safe",
+		"recovery_codes": codes,
+	})
+}
+
+// POST /auth/2fa/recovery-codes - replace the recovery codes, needs a current TOTP code
+func regenerateRecoveryCodes(c *gin.Context) {
+	var req ConfirmTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, _ := auth.UserID(c)
+
+	var username string
+	err := db.QueryRow("SELECT username FROM users WHERE id = ? AND deleted_at IS NULL", userID).Scan(&username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error querying user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if reauthThrottled(c, username) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	ok, err := verifySecondFactor(tx, userID, req.Code, false)
+	if err != nil {
+		log.Printf("Error verifying TOTP code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		recordLoginFailure(username, c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		log.Printf("Error creating recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// POST /auth/2fa/disable - turn 2FA off, needs the password and a TOTP or recovery code
+func disableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, _ := auth.UserID(c)
+
+	var username, passwordHash string
+	var enabled bool
+	err := db.QueryRow(
+		"SELECT username, password_hash, totp_enabled FROM users WHERE id = ? AND deleted_at IS NULL",
+		userID,
+	).Scan(&username, &passwordHash, &enabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error querying user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if reauthThrottled(c, username) {
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil {
+		recordLoginFailure(username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Six digits is a TOTP code, anything else is tried as a recovery code
+	ok, err := verifySecondFactor(tx, userID, req.Code, len(strings.TrimSpace(req.Code)) != 6)
+	if err != nil {
+		log.Printf("Error verifying second factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		recordLoginFailure(username, c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = NULL WHERE id = ?",
+		userID,
+	)
+	if err != nil {
+		log.Printf("Error disabling two-factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	if _, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		log.Printf("Error deleting recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// startLoginChallenge answers a correct password of an enrolled user with a
+// short lived challenge instead of tokens
+func startLoginChallenge(c *gin.Context, user User) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		log.Printf("Error generating login challenge: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	challengeToken := hex.EncodeToString(raw)
+	expiresAt := time.Now().Add(loginChallengeTTL)
+
+	_, err := db.Exec(
+		"INSERT INTO login_challenges (user_id, token_hash, expires_at) VALUES (?, ?, ?)",
+		user.ID, hashToken(challengeToken), expiresAt,
+	)
+	if err != nil {
+		log.Printf("Error storing login challenge: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Two-factor authentication required",
+		"two_factor_required":  true,
+		"challenge_token":      challengeToken,
+		"challenge_expires_at": expiresAt,
+		"methods":              []string{twoFactorMethodTOTP, twoFactorMethodRecovery},
+	})
+}
+
+// POST /auth/login/2fa - second login step, exchanges a challenge and a TOTP
+// or recovery code for the session tokens
+func completeLoginChallenge(c *gin.Context) {
+	var req LoginChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Code == "") == (req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either code or recovery_code"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	var challengeID int64
+	var attempts int
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	var user User
+	err = tx.QueryRow(`
+		SELECT lc.id, lc.attempts, lc.expires_at, lc.used_at,
+		       u.id, u.username, u.email, u.role, u.is_blocked, u.email_verified
+		FROM login_challenges lc
+		JOIN users u ON lc.user_id = u.id
+		WHERE lc.token_hash = ? AND u.deleted_at IS NULL
+		FOR UPDATE
+	`, hashToken(req.ChallengeToken)).Scan(
+		&challengeID, &attempts, &expiresAt, &usedAt,
+		&user.ID, &user.Username, &user.Email, &user.Role, &user.IsBlocked, &user.EmailVerified,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login challenge"})
+			return
+		}
+		log.Printf("Error querying login challenge: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if usedAt.Valid || time.Now().After(expiresAt) || attempts >= maxChallengeAttempts {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login challenge"})
+		return
+	}
+
+	if user.IsBlocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is blocked"})
+		return
+	}
+
+	// Wrong codes count as failed logins, so new challenges do not give
+	// someone who knows the password unlimited guesses
+	retryAfter, err := loginRetryAfter(user.Username, c.ClientIP())
+	if err != nil {
+		log.Printf("Error checking login throttle: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if retryAfter > 0 {
+		tooManyAttempts(c, retryAfter)
+		return
+	}
+
+	code, useRecovery := req.Code, false
+	if req.RecoveryCode != "" {
+		code, useRecovery = req.RecoveryCode, true
+	}
+
+	ok, err := verifySecondFactor(tx, user.ID, code, useRecovery)
+	if err != nil {
+		log.Printf("Error verifying second factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !ok {
+		// Count the failure outside the transaction, which still locks the
+		// challenge row, so the challenge dies after a few wrong codes
+		tx.Rollback()
+		if _, err := db.Exec("UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ?", challengeID); err != nil {
+			log.Printf("Error counting login challenge attempt: %v", err)
+		}
+		recordLoginFailure(user.Username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":              "Invalid code",
+			"attempts_remaining": maxChallengeAttempts - attempts - 1,
+		})
+		return
+	}
+
+	if _, err = tx.Exec("UPDATE login_challenges SET used_at = NOW() WHERE id = ?", challengeID); err != nil {
+		log.Printf("Error completing login challenge: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	tokens, err := createSession(tx, user)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	clearLoginFailures(user.Username)
+
+	c.JSON(http.StatusOK, loginResponse(user, tokens))
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"stakeholders-service/auth"
+	"stakeholders-service/mail"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// One-time account tokens, stored hashed like refresh tokens
+const (
+	tokenVerifyEmail   = "verify_email"
+	tokenResetPassword = "reset_password"
+
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+)
+
+var errInvalidAccountToken = errors.New("invalid or expired token")
+
+var mailer mail.Mailer
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// issueAccountToken creates a token for the purpose, earlier unused tokens of
+// the same purpose stop working so only the latest mail is valid
+func issueAccountToken(exec execer, userID int, purpose string, ttl time.Duration) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	_, err := exec.Exec(
+		"UPDATE account_tokens SET used_at = NOW() WHERE user_id = ? AND purpose = ? AND used_at IS NULL",
+		userID, purpose,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = exec.Exec(
+		"INSERT INTO account_tokens (user_id, purpose, token_hash, expires_at) VALUES (?, ?, ?, ?)",
+		userID, purpose, hashToken(token), time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeAccountToken marks the token used and returns its user, the row is
+// locked so a token can only be consumed once
+func consumeAccountToken(tx *sql.Tx, token, purpose string) (int, error) {
+	var id int64
+	var userID int
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err := tx.QueryRow(`
+		SELECT t.id, t.user_id, t.expires_at, t.used_at
+		FROM account_tokens t
+		JOIN users u ON t.user_id = u.id
+		WHERE t.token_hash = ? AND t.purpose = ? AND u.deleted_at IS NULL
+		FOR UPDATE
+	`, hashToken(token), purpose).Scan(&id, &userID, &expiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return 0, errInvalidAccountToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if usedAt.Valid || time.Now().After(expiresAt) {
+		return 0, errInvalidAccountToken
+	}
+
+	if _, err := tx.Exec("UPDATE account_tokens SET used_at = NOW() WHERE id = ?", id); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// frontendLink builds a link to a frontend page carrying the token
+func frontendLink(page, token string) string {
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:4200"
+	}
+	return fmt.Sprintf("%s/%s?token=%s", baseURL, page, url.QueryEscape(token))
+}
+
+// sendVerificationEmail issues a verification token and mails the link
+func sendVerificationEmail(userID int, username, email string) error {
+	token, err := issueAccountToken(db, userID, tokenVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Verify your SOA Tours email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link is valid for 24 hours. Until then you cannot comment on blogs or buy tours.\n",
+			username, frontendLink("verify-email", token)),
+	})
+}
+
+// POST /auth/verify-email/request - resend the verification mail to the caller
+func requestEmailVerification(c *gin.Context) {
+	userID, _ := auth.UserID(c)
+
+	var username, email string
+	var verified bool
+	err := db.QueryRow(
+		"SELECT username, email, email_verified FROM users WHERE id = ? AND deleted_at IS NULL",
+		userID,
+	).Scan(&username, &email, &verified)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error querying user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if verified {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := sendVerificationEmail(userID, username, email); err != nil {
+		log.Printf("Error sending verification email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// POST /auth/verify-email - consume a verification token
+func verifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeAccountToken(tx, req.Token, tokenVerifyEmail)
+	if err == errInvalidAccountToken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error consuming verification token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE users SET email_verified = TRUE, email_verified_at = NOW() WHERE id = ?",
+		userID,
+	)
+	if err != nil {
+		log.Printf("Error verifying email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	// Access tokens carry the email status, the next refresh picks it up
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully, refresh your session to apply it",
+		"user_id": userID,
+	})
+}
+
+// POST /auth/password-reset/request - mail a reset link. The response is the
+// same whether or not the email belongs to an account, and the lookup and
+// mail happen after it is sent so its timing does not tell either.
+func requestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Counted for unknown emails too, so throttling does not reveal accounts either
+	retryAfter, err := throttleRetryAfter(
+		throttleKey{throttleScopeResetEmail, strings.ToLower(strings.TrimSpace(req.Email))},
+		throttleKey{throttleScopeResetIP, c.ClientIP()},
+	)
+	if err != nil {
+		log.Printf("Error checking password reset throttle: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if retryAfter > 0 {
+		tooManyRequests(c, retryAfter, "Too many password reset requests, try again later")
+		return
+	}
+	recordPasswordResetRequest(req.Email, c.ClientIP())
+
+	go sendPasswordReset(req.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account with that email exists, a password reset link has been sent"})
+}
+
+// sendPasswordReset mails a reset link if email belongs to an active account.
+// Errors are only logged, the client has already been answered.
+func sendPasswordReset(email string) {
+	var userID int
+	var username string
+	err := db.QueryRow(
+		"SELECT id, username, email FROM users WHERE email = ? AND deleted_at IS NULL AND is_blocked = FALSE",
+		email,
+	).Scan(&userID, &username, &email)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying user for password reset: %v", err)
+		return
+	}
+
+	token, err := issueAccountToken(db, userID, tokenResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		log.Printf("Error issuing reset token: %v", err)
+		return
+	}
+
+	err = mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Reset your SOA Tours password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+			"If it was you, open the link below within one hour:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			username, frontendLink("reset-password", token)),
+	})
+	if err != nil {
+		log.Printf("Error sending password reset email: %v", err)
+	}
+}
+
+// POST /auth/password-reset - set a new password with a reset token and end
+// every session of the account
+func resetPassword(c *gin.Context) {
+	var req ConfirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeAccountToken(tx, req.Token, tokenResetPassword)
+	if err == errInvalidAccountToken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error consuming reset token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Receiving the reset mail proves the address, so it counts as verified
+	_, err = tx.Exec(`
+		UPDATE users SET password_hash = ?, email_verified = TRUE,
+		       email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = ?
+	`, string(hashedPassword), userID)
+	if err != nil {
+		log.Printf("Error updating password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if _, err = revokeUserSessions(tx, userID); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please log in again"})
+}